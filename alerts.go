@@ -0,0 +1,314 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "net/smtp"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Notifier delivers a single value bet alert to one destination
+type Notifier interface {
+    Name() string
+    Notify(bet ValueBet) error
+}
+
+// alertTimeout bounds each delivery, so a hung sink can't stall a refresh
+var alertTimeout = 10 * time.Second
+
+var alertHTTPClient = &http.Client{}
+
+func formatAlert(bet ValueBet) string {
+    return fmt.Sprintf("Value bet: %s %+.0f (%s %s)\nGame: %s\nEdge: %.1f%%, Confidence: %.0f%%",
+        betSelection(bet), bet.Odds, bet.Bookmaker, marketLabel(bet.Market), bet.Game, bet.Value*100, bet.Confidence*100)
+}
+
+func postJSON(url string, payload interface{}) error {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := alertHTTPClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("unexpected status %s", resp.Status)
+    }
+    return nil
+}
+
+// webhookNotifier posts the raw ValueBet as JSON to any endpoint
+type webhookNotifier struct {
+    url string
+}
+
+func (n webhookNotifier) Name() string { return "webhook" }
+
+func (n webhookNotifier) Notify(bet ValueBet) error {
+    return postJSON(n.url, map[string]interface{}{
+        "type":    "value_bet",
+        "message": formatAlert(bet),
+        "bet":     bet,
+    })
+}
+
+// slackNotifier uses the Slack incoming webhook payload format
+type slackNotifier struct {
+    url string
+}
+
+func (n slackNotifier) Name() string { return "slack" }
+
+func (n slackNotifier) Notify(bet ValueBet) error {
+    return postJSON(n.url, map[string]string{"text": formatAlert(bet)})
+}
+
+// discordNotifier uses the Discord webhook payload format
+type discordNotifier struct {
+    url string
+}
+
+func (n discordNotifier) Name() string { return "discord" }
+
+func (n discordNotifier) Notify(bet ValueBet) error {
+    return postJSON(n.url, map[string]string{"content": formatAlert(bet)})
+}
+
+// telegramNotifier calls the Bot API sendMessage method
+type telegramNotifier struct {
+    baseURL string
+    token   string
+    chatID  string
+}
+
+func (n telegramNotifier) Name() string { return "telegram" }
+
+func (n telegramNotifier) Notify(bet ValueBet) error {
+    // The token is in the URL; it is registered as a secret so errors are redacted
+    url := fmt.Sprintf("%s/bot%s/sendMessage", n.baseURL, n.token)
+    return postJSON(url, map[string]string{
+        "chat_id": n.chatID,
+        "text":    formatAlert(bet),
+    })
+}
+
+type emailNotifier struct {
+    addr     string
+    username string
+    password string
+    from     string
+    to       []string
+}
+
+func (n emailNotifier) Name() string { return "email" }
+
+func (n emailNotifier) Notify(bet ValueBet) error {
+    msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Value bet: %s (%s)\r\n\r\n%s\r\n",
+        n.from, strings.Join(n.to, ", "), betSelection(bet), bet.Game,
+        strings.ReplaceAll(formatAlert(bet), "\n", "\r\n"))
+    return n.send([]byte(msg))
+}
+
+// send delivers msg like smtp.SendMail, but with the whole exchange bounded
+// by alertTimeout
+func (n emailNotifier) send(msg []byte) error {
+    host, _, err := net.SplitHostPort(n.addr)
+    if err != nil {
+        return err
+    }
+    conn, err := net.DialTimeout("tcp", n.addr, alertTimeout)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    conn.SetDeadline(time.Now().Add(alertTimeout))
+
+    client, err := smtp.NewClient(conn, host)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+
+    if ok, _ := client.Extension("STARTTLS"); ok {
+        if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+            return err
+        }
+    }
+    if n.username != "" {
+        if err := client.Auth(smtp.PlainAuth("", n.username, n.password, host)); err != nil {
+            return err
+        }
+    }
+    if err := client.Mail(n.from); err != nil {
+        return err
+    }
+    for _, to := range n.to {
+        if err := client.Rcpt(to); err != nil {
+            return err
+        }
+    }
+    writer, err := client.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := writer.Write(msg); err != nil {
+        return err
+    }
+    if err := writer.Close(); err != nil {
+        return err
+    }
+    return client.Quit()
+}
+
+// AlertConfig selects notification sinks and when they fire
+type AlertConfig struct {
+    WebhookURL     string
+    SlackURL       string
+    DiscordURL     string
+    TelegramURL    string
+    TelegramToken  string
+    TelegramChatID string
+    SMTPAddr       string
+    SMTPUser       string
+    SMTPPassword   string
+    EmailFrom      string
+    EmailTo        []string
+
+    MinEdge       float64
+    MinConfidence float64
+    Cooldown      time.Duration
+    MaxPerHour    int
+}
+
+func (cfg AlertConfig) notifiers() []Notifier {
+    var notifiers []Notifier
+    if cfg.WebhookURL != "" {
+        notifiers = append(notifiers, webhookNotifier{url: cfg.WebhookURL})
+    }
+    if cfg.SlackURL != "" {
+        notifiers = append(notifiers, slackNotifier{url: cfg.SlackURL})
+    }
+    if cfg.DiscordURL != "" {
+        notifiers = append(notifiers, discordNotifier{url: cfg.DiscordURL})
+    }
+    if cfg.TelegramToken != "" && cfg.TelegramChatID != "" {
+        baseURL := cfg.TelegramURL
+        if baseURL == "" {
+            baseURL = "https://api.telegram.org"
+        }
+        notifiers = append(notifiers, telegramNotifier{
+            baseURL: strings.TrimRight(baseURL, "/"),
+            token:   cfg.TelegramToken,
+            chatID:  cfg.TelegramChatID,
+        })
+    }
+    if cfg.SMTPAddr != "" && cfg.EmailFrom != "" && len(cfg.EmailTo) > 0 {
+        notifiers = append(notifiers, emailNotifier{
+            addr:     cfg.SMTPAddr,
+            username: cfg.SMTPUser,
+            password: cfg.SMTPPassword,
+            from:     cfg.EmailFrom,
+            to:       cfg.EmailTo,
+        })
+    }
+    return notifiers
+}
+
+// alertManager fires notifiers for qualifying bets, at most once per game per
+// cooldown and never more than MaxPerHour in total
+type alertManager struct {
+    cfg       AlertConfig
+    notifiers []Notifier
+    now       func() time.Time
+
+    mu       sync.Mutex
+    lastSent map[string]time.Time
+    sent     []time.Time
+}
+
+// newAlertManager returns nil when no sinks are configured
+func newAlertManager(cfg AlertConfig) *alertManager {
+    notifiers := cfg.notifiers()
+    if len(notifiers) == 0 {
+        return nil
+    }
+    return &alertManager{
+        cfg:       cfg,
+        notifiers: notifiers,
+        now:       time.Now,
+        lastSent:  make(map[string]time.Time),
+    }
+}
+
+// qualifies reports whether bet clears the alert thresholds. Stale prices
+// never alert; the edge may be gone by the time anyone acts on it.
+func (m *alertManager) qualifies(bet ValueBet) bool {
+    return !bet.Stale && bet.Value >= m.cfg.MinEdge && bet.Confidence >= m.cfg.MinConfidence
+}
+
+// process alerts on every qualifying bet that isn't suppressed; bets should
+// be sorted best first so the rate limit keeps the strongest ones. Alerts
+// are sent after the bookkeeping, outside the lock.
+func (m *alertManager) process(bets []ValueBet) {
+    for _, bet := range m.due(bets) {
+        for _, n := range m.notifiers {
+            if err := n.Notify(bet); err != nil {
+                log.Printf("%s alert failed: %v", n.Name(), err)
+            }
+        }
+    }
+}
+
+// due picks the bets to alert on and records them as sent
+func (m *alertManager) due(bets []ValueBet) []ValueBet {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    now := m.now()
+    recent := m.sent[:0]
+    for _, t := range m.sent {
+        if now.Sub(t) < time.Hour {
+            recent = append(recent, t)
+        }
+    }
+    m.sent = recent
+
+    var due []ValueBet
+    for _, bet := range bets {
+        if !m.qualifies(bet) {
+            continue
+        }
+        if last, ok := m.lastSent[bet.GameID]; ok && now.Sub(last) < m.cfg.Cooldown {
+            continue
+        }
+        if m.cfg.MaxPerHour > 0 && len(m.sent) >= m.cfg.MaxPerHour {
+            log.Printf("alert rate limit reached, suppressing %s", bet.Team)
+            continue
+        }
+
+        m.lastSent[bet.GameID] = now
+        m.sent = append(m.sent, now)
+        due = append(due, bet)
+    }
+    return due
+}
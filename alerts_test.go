@@ -0,0 +1,298 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+var testAlertBet = ValueBet{
+    Game:       "Boston Celtics vs New York Knicks",
+    GameID:     "game1",
+    Bookmaker:  "fanduel",
+    Market:     "h2h",
+    Team:       "Boston Celtics",
+    Odds:       150,
+    Value:      0.08,
+    Confidence: 0.9,
+}
+
+// captureServer records the path and JSON body of the last request
+func captureServer(t *testing.T) (*httptest.Server, *string, *map[string]interface{}) {
+    t.Helper()
+    var path string
+    body := map[string]interface{}{}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        path = r.URL.Path
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decoding body: %v", err)
+        }
+    }))
+    t.Cleanup(server.Close)
+    return server, &path, &body
+}
+
+func TestHTTPNotifierPayloads(t *testing.T) {
+    tests := []struct {
+        name  string
+        build func(url string) Notifier
+        path  string
+        check func(t *testing.T, body map[string]interface{})
+    }{
+        {
+            name:  "webhook",
+            build: func(url string) Notifier { return webhookNotifier{url: url} },
+            path:  "/",
+            check: func(t *testing.T, body map[string]interface{}) {
+                if body["type"] != "value_bet" {
+                    t.Errorf("type = %v, want value_bet", body["type"])
+                }
+                bet, _ := body["bet"].(map[string]interface{})
+                if bet["team"] != testAlertBet.Team || bet["game_id"] != testAlertBet.GameID {
+                    t.Errorf("bet = %v, want the value bet", bet)
+                }
+            },
+        },
+        {
+            name:  "slack",
+            build: func(url string) Notifier { return slackNotifier{url: url} },
+            path:  "/",
+            check: func(t *testing.T, body map[string]interface{}) {
+                if body["text"] != formatAlert(testAlertBet) {
+                    t.Errorf("text = %v", body["text"])
+                }
+            },
+        },
+        {
+            name:  "discord",
+            build: func(url string) Notifier { return discordNotifier{url: url} },
+            path:  "/",
+            check: func(t *testing.T, body map[string]interface{}) {
+                if body["content"] != formatAlert(testAlertBet) {
+                    t.Errorf("content = %v", body["content"])
+                }
+            },
+        },
+        {
+            name: "telegram",
+            build: func(url string) Notifier {
+                return telegramNotifier{baseURL: url, token: "123:abc", chatID: "42"}
+            },
+            path: "/bot123:abc/sendMessage",
+            check: func(t *testing.T, body map[string]interface{}) {
+                if body["chat_id"] != "42" || body["text"] != formatAlert(testAlertBet) {
+                    t.Errorf("body = %v", body)
+                }
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server, path, body := captureServer(t)
+            if err := tt.build(server.URL).Notify(testAlertBet); err != nil {
+                t.Fatalf("Notify: %v", err)
+            }
+            if *path != tt.path {
+                t.Errorf("path = %q, want %q", *path, tt.path)
+            }
+            tt.check(t, *body)
+        })
+    }
+}
+
+func TestHTTPNotifierErrorStatus(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusInternalServerError)
+    }))
+    defer server.Close()
+
+    if err := (slackNotifier{url: server.URL}).Notify(testAlertBet); err == nil {
+        t.Error("Notify succeeded against a failing endpoint")
+    }
+}
+
+func TestHTTPNotifierTimesOut(t *testing.T) {
+    release := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-release
+    }))
+    defer server.Close()
+    defer close(release)
+
+    defer func(timeout time.Duration) { alertTimeout = timeout }(alertTimeout)
+    alertTimeout = 100 * time.Millisecond
+
+    start := time.Now()
+    if err := (webhookNotifier{url: server.URL}).Notify(testAlertBet); err == nil {
+        t.Fatal("Notify succeeded against a hung endpoint")
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("Notify took %s against a hung endpoint", elapsed)
+    }
+}
+
+// stubSMTP accepts one session, speaking just enough SMTP for net/smtp, and
+// sends the envelope and message it received on the returned channel
+func stubSMTP(t *testing.T) (string, <-chan []string) {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+
+    received := make(chan []string, 1)
+    go func() {
+        conn, err := listener.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        reader := bufio.NewReader(conn)
+        reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+        var session []string
+        reply("220 stub ready")
+        for {
+            line, err := reader.ReadString('\n')
+            if err != nil {
+                return
+            }
+            line = strings.TrimRight(line, "\r\n")
+            switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+            case "EHLO", "HELO":
+                reply("250 stub")
+            case "MAIL", "RCPT":
+                session = append(session, line)
+                reply("250 ok")
+            case "DATA":
+                reply("354 go ahead")
+                var msg strings.Builder
+                for {
+                    dataLine, err := reader.ReadString('\n')
+                    if err != nil {
+                        return
+                    }
+                    if dataLine == ".\r\n" {
+                        break
+                    }
+                    msg.WriteString(dataLine)
+                }
+                session = append(session, msg.String())
+                reply("250 queued")
+            case "QUIT":
+                reply("221 bye")
+                received <- session
+                return
+            default:
+                reply("502 not implemented")
+            }
+        }
+    }()
+    return listener.Addr().String(), received
+}
+
+func TestEmailNotifier(t *testing.T) {
+    addr, received := stubSMTP(t)
+    n := emailNotifier{addr: addr, from: "bets@example.com", to: []string{"me@example.com"}}
+    if err := n.Notify(testAlertBet); err != nil {
+        t.Fatalf("Notify: %v", err)
+    }
+
+    session := <-received
+    if len(session) != 3 {
+        t.Fatalf("session = %q, want MAIL, RCPT and a message", session)
+    }
+    if session[0] != "MAIL FROM:<bets@example.com>" || session[1] != "RCPT TO:<me@example.com>" {
+        t.Errorf("envelope = %q", session[:2])
+    }
+    msg := session[2]
+    if !strings.Contains(msg, "Subject: Value bet: Boston Celtics (Boston Celtics vs New York Knicks)") {
+        t.Errorf("message has no subject line:\n%s", msg)
+    }
+    if !strings.Contains(msg, "Edge: 8.0%, Confidence: 90%") {
+        t.Errorf("message has no alert body:\n%s", msg)
+    }
+}
+
+func TestEmailNotifierTimesOut(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer listener.Close()
+    // Accept and never greet
+    go func() {
+        conn, err := listener.Accept()
+        if err == nil {
+            defer conn.Close()
+            time.Sleep(5 * time.Second)
+        }
+    }()
+
+    defer func(timeout time.Duration) { alertTimeout = timeout }(alertTimeout)
+    alertTimeout = 100 * time.Millisecond
+
+    start := time.Now()
+    n := emailNotifier{addr: listener.Addr().String(), from: "bets@example.com", to: []string{"me@example.com"}}
+    if err := n.Notify(testAlertBet); err == nil {
+        t.Fatal("Notify succeeded against a silent server")
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("Notify took %s against a silent server", elapsed)
+    }
+}
+
+func TestAlertManagerCooldownAndLimit(t *testing.T) {
+    var sent []string
+    now := time.Date(2025, 1, 10, 19, 0, 0, 0, time.UTC)
+    m := &alertManager{
+        cfg:       AlertConfig{MinEdge: 0.05, MinConfidence: 0.8, Cooldown: 30 * time.Minute, MaxPerHour: 2},
+        notifiers: []Notifier{recordingNotifier{&sent}},
+        now:       func() time.Time { return now },
+        lastSent:  make(map[string]time.Time),
+    }
+
+    other := testAlertBet
+    other.GameID, other.Team = "game2", "Miami Heat"
+    third := testAlertBet
+    third.GameID, third.Team = "game3", "Chicago Bulls"
+    weak := testAlertBet
+    weak.GameID, weak.Value = "game4", 0.01
+
+    m.process([]ValueBet{testAlertBet, weak, other, third})
+    if want := []string{"Boston Celtics", "Miami Heat"}; strings.Join(sent, ",") != strings.Join(want, ",") {
+        t.Errorf("sent %v, want %v", sent, want)
+    }
+
+    // Still cooling down and still at the hourly limit
+    now = now.Add(10 * time.Minute)
+    m.process([]ValueBet{testAlertBet, third})
+    if len(sent) != 2 {
+        t.Errorf("sent %v after ten minutes, want nothing more", sent)
+    }
+
+    now = now.Add(time.Hour)
+    m.process([]ValueBet{testAlertBet})
+    if len(sent) != 3 {
+        t.Errorf("sent %v after the cooldown, want the bet again", sent)
+    }
+}
+
+type recordingNotifier struct {
+    sent *[]string
+}
+
+func (n recordingNotifier) Name() string { return "recording" }
+
+func (n recordingNotifier) Notify(bet ValueBet) error {
+    *n.sent = append(*n.sent, bet.Team)
+    return nil
+}
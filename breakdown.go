@@ -0,0 +1,34 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+)
+
+// BreakdownStep is one term of a bet's model probability. Value is what the
+// term added and Total the running probability after it.
+type BreakdownStep struct {
+    Factor string  `json:"factor"`
+    Value  float64 `json:"value"`
+    Total  float64 `json:"total"`
+}
+
+// addStep appends a term to the waterfall, carrying the running total
+func addStep(steps []BreakdownStep, factor string, value float64) []BreakdownStep {
+    total := value
+    if len(steps) > 0 {
+        total += steps[len(steps)-1].Total
+    }
+    return append(steps, BreakdownStep{Factor: factor, Value: value, Total: total})
+}
+
+// displayBreakdown prints the waterfall from the first term to the edge
+func displayBreakdown(bet ValueBet) {
+    fmt.Printf("\nPROBABILITY BREAKDOWN:\n")
+    for _, step := range bet.Breakdown {
+        fmt.Printf("  %-20s %+7.1f%%  %6.1f%%\n", strings.ReplaceAll(step.Factor, "_", " "), step.Value*100, step.Total*100)
+    }
+    fmt.Printf("  %-20s %8s  %6.1f%%\n", "= model probability", "", bet.HistoricalProb*100)
+    fmt.Printf("  %-20s %8s  %6.1f%%\n", "- implied by odds", "", bet.ImpliedProb*100)
+    fmt.Printf("  %-20s %8s  %6.1f%%\n", "= edge", "", bet.Value*100)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// cacheEntry is one cached upstream response plus the validators needed to
+// revalidate it with a conditional request
+type cacheEntry struct {
+    FetchedAt    time.Time `json:"fetched_at"`
+    ETag         string    `json:"etag,omitempty"`
+    LastModified string    `json:"last_modified,omitempty"`
+    Body         []byte    `json:"body"`
+}
+
+func (e cacheEntry) fresh(ttl time.Duration, now time.Time) bool {
+    return now.Sub(e.FetchedAt) < ttl
+}
+
+// responseCache keeps responses in memory and, when dir is set, on disk so
+// they survive between runs
+type responseCache struct {
+    dir string
+    now func() time.Time
+
+    mu  sync.Mutex
+    mem map[string]cacheEntry
+}
+
+// cache is the shared response cache, nil when caching is disabled
+var cache *responseCache
+
+func newResponseCache(dir string) *responseCache {
+    return &responseCache{
+        dir: dir,
+        now: time.Now,
+        mem: make(map[string]cacheEntry),
+    }
+}
+
+// cacheKey identifies a response by source and request parameters. Callers
+// must leave credentials out of parts.
+func cacheKey(parts ...string) string {
+    sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+    return hex.EncodeToString(sum[:])
+}
+
+// cacheTTL is how long responses from source stay fresh
+func cacheTTL(source string) time.Duration {
+    switch source {
+    case "sports":
+        return config.CacheTTLSports
+    case "stats", "schedule", "players", "gamelogs":
+        return config.CacheTTLStats
+    case "odds":
+        return config.CacheTTLOdds
+    case "live", "scores":
+        return config.CacheTTLLive
+    }
+    return 0
+}
+
+func (c *responseCache) get(key string) (cacheEntry, bool) {
+    if c == nil {
+        return cacheEntry{}, false
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if entry, ok := c.mem[key]; ok {
+        return entry, true
+    }
+    if c.dir == "" {
+        return cacheEntry{}, false
+    }
+
+    data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+    if err != nil {
+        return cacheEntry{}, false
+    }
+    var entry cacheEntry
+    if err := json.Unmarshal(data, &entry); err != nil {
+        return cacheEntry{}, false
+    }
+    c.mem[key] = entry
+    return entry, true
+}
+
+// lookup returns the entry only if it is still fresh for source
+func (c *responseCache) lookup(source, key string) ([]byte, bool) {
+    if c == nil {
+        return nil, false
+    }
+    entry, ok := c.get(key)
+    if !ok || !entry.fresh(cacheTTL(source), c.now()) {
+        return nil, false
+    }
+    return entry.Body, true
+}
+
+// put stores entry; disk write failures only cost us the persistent copy
+func (c *responseCache) put(key string, entry cacheEntry) {
+    if c == nil {
+        return
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.mem[key] = entry
+    if c.dir == "" {
+        return
+    }
+    data, err := json.Marshal(entry)
+    if err != nil {
+        return
+    }
+    if err := os.MkdirAll(c.dir, 0700); err != nil {
+        return
+    }
+    tmp := filepath.Join(c.dir, key+".tmp")
+    if err := os.WriteFile(tmp, data, 0600); err != nil {
+        return
+    }
+    os.Rename(tmp, filepath.Join(c.dir, key+".json"))
+}
+
+func (c *responseCache) store(key string, body []byte) {
+    if c == nil {
+        return
+    }
+    c.put(key, cacheEntry{FetchedAt: c.now(), Body: body})
+}
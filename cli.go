@@ -0,0 +1,789 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "log"
+    "math"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Exit codes shared by every subcommand
+const (
+    exitOK    = 0
+    exitError = 1
+    exitUsage = 2
+)
+
+type command struct {
+    name    string
+    summary string
+    run     func(ctx context.Context, args []string) error
+}
+
+func commandList() []command {
+    return []command{
+        {"sports", "List sports available from the odds API", runSports},
+        {"odds", "Show current odds from the configured bookmakers", runOdds},
+        {"live", "Show the live NBA scoreboard", runLive},
+        {"analyze", "Find value bets once and print the best ones", runAnalyze},
+        {"watch", "Re-run the analysis on an interval and print changes", runWatch},
+        {"backtest", "Evaluate recorded recommendations against their results", runBacktest},
+        {"settle", "Settle open bets in the ledger from final scores", runSettle},
+        {"report", "Summarize the bet ledger", runReport},
+        {"ratings", "Show opponent-adjusted team ratings", runRatings},
+        {"parlay", "Suggest parlays of current value bets within a stake budget", runParlay},
+        {"hedge", "Value open bets and work out hedge stakes or check a cash-out offer", runHedge},
+        {"serve", "Serve the analysis over HTTP", runServe},
+        {"keys", "List or encrypt odds API keys", runKeys},
+    }
+}
+
+// usageError marks failures caused by bad invocation rather than upstream data
+type usageError struct {
+    msg string
+}
+
+func (e usageError) Error() string { return e.msg }
+
+func progName() string {
+    return filepath.Base(os.Args[0])
+}
+
+func printUsage() {
+    fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", progName())
+    for _, cmd := range commandList() {
+        fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+    }
+    fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for command flags.\n", progName())
+}
+
+// runCLI dispatches to a subcommand and maps its error to an exit code
+func runCLI(args []string) int {
+    log.SetOutput(redactingWriter{os.Stderr})
+
+    if len(args) == 0 {
+        printUsage()
+        return exitUsage
+    }
+    if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+        printUsage()
+        return exitOK
+    }
+
+    for _, cmd := range commandList() {
+        if cmd.name != args[0] {
+            continue
+        }
+
+        ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+        err := cmd.run(ctx, args[1:])
+        stop()
+        var usageErr usageError
+        switch {
+        case err == nil:
+            return exitOK
+        case errors.Is(err, flag.ErrHelp):
+            return exitOK
+        case errors.As(err, &usageErr):
+            if usageErr.msg != "" {
+                fmt.Fprintf(os.Stderr, "Error: %v\n", redact(err.Error()))
+            }
+            return exitUsage
+        default:
+            fmt.Fprintf(os.Stderr, "Error: %v\n", redact(err.Error()))
+            return exitError
+        }
+    }
+
+    fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+    printUsage()
+    return exitUsage
+}
+
+// newFlagSet creates a subcommand flag set with help text and the shared config flags
+func newFlagSet(name, description string) (*flag.FlagSet, *configFlags) {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.Usage = func() {
+        fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", progName(), name, description)
+        fs.PrintDefaults()
+    }
+    return fs, addConfigFlags(fs)
+}
+
+// windowFlags are shorthands for window_start and window_days
+type windowFlags struct {
+    date *string
+    days *int
+}
+
+func addWindowFlags(fs *flag.FlagSet) *windowFlags {
+    return &windowFlags{
+        date: fs.String("date", "", "only games tipping off on or after this date, YYYY-MM-DD or today"),
+        days: fs.Int("days", 0, "number of days from -date to include"),
+    }
+}
+
+// apply overrides the loaded config with any window flags that were given
+func (w *windowFlags) apply() error {
+    if *w.date != "" {
+        config.WindowStart = *w.date
+    }
+    if *w.days > 0 {
+        config.WindowDays = *w.days
+    }
+    if _, _, _, err := gameWindow(time.Now()); err != nil {
+        return usageError{err.Error()}
+    }
+    return nil
+}
+
+// parseFlags parses args and loads the configuration they select
+func parseFlags(fs *flag.FlagSet, configFlags *configFlags, args []string) error {
+    if err := fs.Parse(args); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            return err
+        }
+        // The flag package has already printed the error and usage
+        return usageError{}
+    }
+    if fs.NArg() > 0 {
+        return usageError{fmt.Sprintf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+    }
+    if err := configFlags.load(); err != nil {
+        return usageError{fmt.Sprintf("error loading config: %v", err)}
+    }
+    return nil
+}
+
+func printJSON(v interface{}) error {
+    output, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return fmt.Errorf("error encoding output: %v", err)
+    }
+    fmt.Println(string(output))
+    return nil
+}
+
+func runSports(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("sports", "Lists the sports the odds API currently offers.")
+    all := fs.Bool("all", false, "include sports that are out of season")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+    sports, err := fetchSports(ctx, client)
+    if err != nil {
+        return fmt.Errorf("error fetching sports: %v", err)
+    }
+
+    var listed []Sport
+    for _, sport := range sports {
+        if sport.Active || *all {
+            listed = append(listed, sport)
+        }
+    }
+
+    if config.Output == "json" {
+        return printJSON(listed)
+    }
+    fmt.Println("Available Sports:")
+    for _, sport := range listed {
+        fmt.Printf("- %s (%s)\n", sport.Title, sport.Key)
+    }
+    return nil
+}
+
+func runOdds(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("odds", "Shows current odds for the configured sport, bookmakers and markets.")
+    team := fs.String("team", "", "only show games involving this team (case-insensitive substring)")
+    window := addWindowFlags(fs)
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if err := window.apply(); err != nil {
+        return err
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+    games, err := fetchOdds(ctx, client, config.Sport)
+    if err != nil {
+        return fmt.Errorf("error fetching odds: %v", err)
+    }
+    games = filterGamesByTeam(games, *team)
+
+    if config.Output == "json" {
+        return printJSON(games)
+    }
+    fmt.Println("Current Odds:")
+    displayBookmakerOdds(games)
+    return nil
+}
+
+func filterGamesByTeam(games []Game, team string) []Game {
+    if team == "" {
+        return games
+    }
+    team = strings.ToLower(team)
+
+    var filtered []Game
+    for _, game := range games {
+        if strings.Contains(strings.ToLower(game.HomeTeam), team) ||
+            strings.Contains(strings.ToLower(game.AwayTeam), team) {
+            filtered = append(filtered, game)
+        }
+    }
+    return filtered
+}
+
+func runLive(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("live", "Shows today's NBA scoreboard from nba_stats_fetcher.py.")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+
+    liveScores, err := fetchLiveScores(ctx)
+    if err != nil {
+        return fmt.Errorf("error fetching live scores: %v", err)
+    }
+
+    if config.Output == "json" {
+        return printJSON(liveScores)
+    }
+    displayLiveGames(liveScores)
+    return nil
+}
+
+func displayLiveGames(liveScores map[string]LiveGameState) {
+    if len(liveScores) == 0 {
+        fmt.Println("No games on the scoreboard")
+        return
+    }
+
+    gameKeys := make([]string, 0, len(liveScores))
+    for gameKey := range liveScores {
+        gameKeys = append(gameKeys, gameKey)
+    }
+    sort.Strings(gameKeys)
+
+    fmt.Println("Current Live Games:")
+    for _, gameKey := range gameKeys {
+        liveGame := liveScores[gameKey]
+        fmt.Printf("\n%s:\n", gameKey)
+        fmt.Printf("Period: %d, Clock: %s\n", liveGame.Period, liveGame.Clock)
+        fmt.Printf("Score: %s %d - %d %s\n",
+            liveGame.HomeTeam, liveGame.HomeScore,
+            liveGame.AwayScore, liveGame.AwayTeam)
+    }
+}
+
+func runAnalyze(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("analyze", "Fetches stats, live scores and odds, then prints the best value bets.")
+    record := fs.Bool("record", false, "append the recommended bets to the ledger as open bets")
+    stake := fs.Float64("stake", 0, "flat stake recorded for each bet with -record, capped at its suggested stake (default the suggested stake)")
+    explain := fs.Bool("explain", false, "print each bet's probability breakdown (same as -set explain=true)")
+    window := addWindowFlags(fs)
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if *explain {
+        config.Explain = true
+    }
+    if err := window.apply(); err != nil {
+        return err
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+
+    progressf("Fetching NBA stats, live scores and odds...\n")
+    result, err := runPipeline(ctx, client)
+    if err != nil {
+        return err
+    }
+    progressf("Successfully loaded data for %d teams, %d live games and odds for %d games\n",
+        len(result.Stats), len(result.LiveScores), len(result.Games))
+    if degraded := result.degradedSummary(); degraded != "" {
+        progressf("WARNING: degraded sources: %s\n", degraded)
+    }
+    games := result.Games
+
+    if config.ShowOdds && config.Output == "text" {
+        fmt.Println("\nCurrent Odds:")
+        displayBookmakerOdds(games)
+    }
+
+    progressf("\nAnalyzing value betting opportunities...\n")
+    valueBets := analyzeValueBets(games, result.Stats, result.LiveScores, result.Schedule, result.PlayerLogs)
+
+    if config.ShowLive && config.Output == "text" && len(result.LiveScores) > 0 {
+        fmt.Println()
+        displayLiveGames(result.LiveScores)
+    }
+
+    if *record && len(valueBets) > 0 {
+        now := time.Now()
+        var entries []LedgerEntry
+        for _, bet := range valueBets {
+            // Bets with no Kelly edge or trimmed to nothing by the exposure
+            // limits aren't placed
+            if bet.Stake <= 0 {
+                continue
+            }
+            // A flat stake never goes over what the exposure limits allow
+            betStake := bet.Stake
+            if *stake > 0 {
+                betStake = math.Min(*stake, bet.Stake)
+            }
+            entries = append(entries, newLedgerEntry(bet, betStake, now, len(entries)))
+        }
+        if err := appendLedger(config.LedgerPath, entries); err != nil {
+            return err
+        }
+        progressf("\nRecorded %d bets in %s\n", len(entries), config.LedgerPath)
+    }
+
+    progressf("\nAnalysis complete!\n")
+    return nil
+}
+
+func runWatch(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("watch",
+        "Refreshes the analysis on an interval, printing value bets as they appear, move or disappear.\n"+
+            "Configured alert sinks fire for qualifying bets.")
+    interval := fs.Duration("interval", 60*time.Second, "time between refreshes")
+    showLive := fs.Bool("live", false, "also print live score changes")
+    window := addWindowFlags(fs)
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if err := window.apply(); err != nil {
+        return err
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+
+    store := newDataStore(client)
+    store.alerts = newAlertManager(config.Alerts)
+
+    ticker := time.NewTicker(*interval)
+    defer ticker.Stop()
+
+    var cursor uint64
+    for {
+        if err := store.refresh(ctx); err != nil {
+            fmt.Fprintf(os.Stderr, "%s refresh failed: %v\n", time.Now().Format("15:04:05"), err)
+        }
+
+        events, _ := store.events.since(cursor)
+        for _, ev := range events {
+            cursor = ev.ID
+            if ev.Type == eventLiveGame && !*showLive {
+                continue
+            }
+            if config.Output == "json" {
+                if err := json.NewEncoder(os.Stdout).Encode(ev); err != nil {
+                    return fmt.Errorf("error encoding event: %v", err)
+                }
+                continue
+            }
+            printWatchEvent(ev)
+        }
+
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+        }
+    }
+}
+
+func printWatchEvent(ev streamEvent) {
+    stamp := ev.Time.Format("15:04:05")
+    switch data := ev.Data.(type) {
+    case ValueBet:
+        verb := "NEW"
+        if ev.Type == eventValueBetRemoved {
+            verb = "GONE"
+        }
+        stale := ""
+        if data.Stale {
+            stale = " [STALE: " + data.StaleReason + "]"
+        }
+        fmt.Printf("%s %-4s %s %+.0f (%s) edge %.1f%% confidence %.3f - %s%s\n",
+            stamp, verb, betSelection(data), data.Odds, data.Bookmaker, data.Value*100, data.Confidence, data.Game, stale)
+    case valueBetChange:
+        fmt.Printf("%s MOVE %s %+.0f -> %+.0f (%s) edge %.1f%% -> %.1f%% - %s\n",
+            stamp, betSelection(data.Current), data.Previous.Odds, data.Current.Odds, data.Current.Bookmaker,
+            data.Previous.Value*100, data.Current.Value*100, data.Current.Game)
+    case LiveGameState:
+        fmt.Printf("%s LIVE %s %d - %d %s (Q%d %s)\n",
+            stamp, data.HomeTeam, data.HomeScore, data.AwayScore, data.AwayTeam, data.Period, data.Clock)
+    }
+}
+
+// confidenceTiers are the recommendation bands, best first
+var confidenceTiers = []string{"Strong", "Moderate", "Speculative"}
+
+// confidenceTier names the recommendation band P(edge > 0) falls in
+func confidenceTier(confidence float64) string {
+    if confidence >= config.StrongConfidence {
+        return "Strong"
+    } else if confidence >= config.ModerateConfidence {
+        return "Moderate"
+    }
+    return "Speculative"
+}
+
+// betTier is bet's recommendation band: its confidence tier, capped at
+// stale_tier when its price is stale
+func betTier(bet ValueBet) string {
+    tier := confidenceTier(bet.Confidence)
+    if bet.Stale && tierRank(tier) < tierRank(config.StaleTier) {
+        return confidenceTiers[tierRank(config.StaleTier)]
+    }
+    return tier
+}
+
+// tierRank is tier's position in confidenceTiers, best first
+func tierRank(tier string) int {
+    for i, name := range confidenceTiers {
+        if strings.EqualFold(name, tier) {
+            return i
+        }
+    }
+    return len(confidenceTiers)
+}
+
+func runBacktest(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("backtest",
+        "Replays settled ledger entries under the current thresholds and reports results per confidence tier.")
+    minEdge := fs.Float64("min-edge", -1, "only count bets with at least this edge (default: config min_edge)")
+    minConfidence := fs.Float64("min-confidence", 0, "only count bets with at least this confidence")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if *minEdge < 0 {
+        *minEdge = config.MinEdge
+    }
+
+    entries, err := loadLedger(config.LedgerPath)
+    if err != nil {
+        return err
+    }
+
+    tiers := map[string][]LedgerEntry{}
+    var selected []LedgerEntry
+    for _, entry := range entries {
+        if entry.Status == betOpen || entry.Value < *minEdge || entry.Confidence < *minConfidence {
+            continue
+        }
+        selected = append(selected, entry)
+        tier := confidenceTier(entry.Confidence)
+        tiers[tier] = append(tiers[tier], entry)
+    }
+
+    if config.Output == "json" {
+        results := map[string]ledgerSummary{"All": summarizeLedger(selected)}
+        for tier, tierEntries := range tiers {
+            results[tier] = summarizeLedger(tierEntries)
+        }
+        return printJSON(results)
+    }
+
+    fmt.Printf("Backtest of %s (min edge %.1f%%, min confidence %.3f)\n",
+        config.LedgerPath, *minEdge*100, *minConfidence)
+    summarizeLedger(selected).print("All Bets")
+    for _, tier := range confidenceTiers {
+        if len(tiers[tier]) > 0 {
+            summarizeLedger(tiers[tier]).print(tier + " Bets")
+        }
+    }
+    return nil
+}
+
+func runSettle(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("settle",
+        "Settles open moneyline, spread and total bets from the odds API final scores, or one bet by hand with -id and -result.")
+    days := fs.Int("days", 3, "days of completed games to fetch (1-3)")
+    id := fs.String("id", "", "settle only the bet with this ledger ID")
+    result := fs.String("result", "", "manual result for -id: won, lost or push")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if (*id == "") != (*result == "") {
+        return usageError{"-id and -result must be used together"}
+    }
+    if *result != "" && *result != betWon && *result != betLost && *result != betPush {
+        return usageError{fmt.Sprintf("invalid -result %q", *result)}
+    }
+    if *days < 1 || *days > 3 {
+        return usageError{fmt.Sprintf("invalid -days %d: must be 1-3", *days)}
+    }
+
+    entries, err := loadLedger(config.LedgerPath)
+    if err != nil {
+        return err
+    }
+
+    now := time.Now()
+    settled := 0
+    if *id != "" {
+        for i := range entries {
+            if entries[i].ID == *id {
+                entries[i].settle(*result, now)
+                settled++
+            }
+        }
+        if settled == 0 {
+            return fmt.Errorf("no bet with ID %q in %s", *id, config.LedgerPath)
+        }
+    } else {
+        client, err := initClient()
+        if err != nil {
+            return err
+        }
+        scores, err := fetchScores(ctx, client, config.Sport, *days)
+        if err != nil {
+            return fmt.Errorf("error fetching scores: %v", err)
+        }
+        settled = settleFromScores(entries, scores, now)
+    }
+
+    if settled > 0 {
+        if err := saveLedger(config.LedgerPath, entries); err != nil {
+            return err
+        }
+    }
+    fmt.Printf("Settled %d bets\n", settled)
+    return nil
+}
+
+func runReport(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("report", "Summarizes profit, ROI and open positions in the bet ledger.")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+
+    entries, err := loadLedger(config.LedgerPath)
+    if err != nil {
+        return err
+    }
+
+    byBook := map[string][]LedgerEntry{}
+    var open []LedgerEntry
+    for _, entry := range entries {
+        byBook[entry.Bookmaker] = append(byBook[entry.Bookmaker], entry)
+        if entry.Status == betOpen {
+            open = append(open, entry)
+        }
+    }
+
+    if config.Output == "json" {
+        return printJSON(map[string]interface{}{
+            "summary": summarizeLedger(entries),
+            "open":    open,
+        })
+    }
+
+    fmt.Printf("Bet Ledger Report (%s)\n", config.LedgerPath)
+    summarizeLedger(entries).print("All Bets")
+
+    books := make([]string, 0, len(byBook))
+    for book := range byBook {
+        books = append(books, book)
+    }
+    sort.Strings(books)
+    for _, book := range books {
+        summarizeLedger(byBook[book]).print(book)
+    }
+
+    if len(open) > 0 {
+        fmt.Printf("\nOpen Bets:\n")
+        for _, entry := range open {
+            fmt.Printf("%s  %s %+.0f  stake %.2f  %s\n", entry.ID, entry.Team, entry.Odds, entry.Stake, entry.Game)
+        }
+    }
+    return nil
+}
+
+func runRatings(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("ratings",
+        "Solves opponent-adjusted offensive and defensive ratings per 100 possessions from the season's games.")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+
+    table, err := fetchRatings(ctx)
+    if err != nil {
+        return fmt.Errorf("error rating teams: %v", err)
+    }
+    if config.Output == "json" {
+        return printJSON(table)
+    }
+
+    names := make([]string, 0, len(table.Teams))
+    for name := range table.Teams {
+        names = append(names, name)
+    }
+    sort.Slice(names, func(i, j int) bool { return table.Teams[names[i]].Net > table.Teams[names[j]].Net })
+
+    fmt.Printf("Team Ratings per 100 possessions (league %.1f, pace %.1f, home edge %+.1f)\n",
+        table.LeagueRating, table.LeaguePace, table.HomeEdge)
+    fmt.Printf("%-4s %-24s %6s %6s %6s %6s %5s\n", "#", "Team", "ORtg", "DRtg", "Net", "Pace", "GP")
+    for i, name := range names {
+        rating := table.Teams[name]
+        fmt.Printf("%-4d %-24s %6.1f %6.1f %+6.1f %6.1f %5d\n",
+            i+1, name, rating.Offense, rating.Defense, rating.Net, rating.Pace, rating.Games)
+    }
+    return nil
+}
+
+func runParlay(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("parlay",
+        "Combines the current value bets into parlays, priced with same-game correlation, and stakes the best within a budget.")
+    legs := fs.Int("legs", 3, "most legs per parlay (2 or 3)")
+    budget := fs.Float64("budget", 0, "total stake across all parlays (default bankroll * max_stake)")
+    window := addWindowFlags(fs)
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if *legs < 2 || *legs > 3 {
+        return usageError{"-legs must be 2 or 3"}
+    }
+    if err := window.apply(); err != nil {
+        return err
+    }
+    if *budget <= 0 {
+        *budget = config.Bankroll * config.MaxStake
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+    progressf("Fetching NBA stats, live scores and odds...\n")
+    result, err := runPipeline(ctx, client)
+    if err != nil {
+        return err
+    }
+    if degraded := result.degradedSummary(); degraded != "" {
+        progressf("WARNING: degraded sources: %s\n", degraded)
+    }
+
+    valueBets := findValueBets(result.Games, result.Stats, result.LiveScores, result.Schedule, result.PlayerLogs)
+    pricer := newParlayPricer(result.Games, result.Stats, result.LiveScores, result.Schedule)
+    parlays, sameGame := buildParlays(valueBets, *legs, *budget, pricer, openLedgerEntries())
+
+    if config.Output == "json" {
+        return printJSON(map[string][]Parlay{"parlays": parlays, "same_game": sameGame})
+    }
+    if len(parlays) == 0 {
+        fmt.Println("No positive expected value parlays among the current value bets")
+    } else {
+        fmt.Printf("\nBest Parlays (budget %.2f):\n", *budget)
+        fmt.Printf("=============================\n")
+        for i, parlay := range parlays {
+            displayParlay(i+1, parlay)
+        }
+    }
+    if len(sameGame) > 0 {
+        fmt.Printf("\nSame-Game Parlays (not staked - books price these below the legs multiplied):\n")
+        fmt.Printf("=============================\n")
+        for i, parlay := range sameGame {
+            displayParlay(i+1, parlay)
+        }
+    }
+    return nil
+}
+
+func runHedge(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("hedge",
+        "Values open bets at the model's current probability and works out hedge stakes against the best live price.")
+    id := fs.String("id", "", "only the open bet with this ledger ID")
+    cashOut := fs.Float64("cashout", 0, "cash-out amount the book offers for -id, to compare with fair value")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+    if *cashOut < 0 || *cashOut > 0 && *id == "" {
+        return usageError{"-cashout needs -id and must not be negative"}
+    }
+
+    entries, err := loadLedger(config.LedgerPath)
+    if err != nil {
+        return err
+    }
+    var open []LedgerEntry
+    for _, entry := range entries {
+        if entry.Status == betOpen && (*id == "" || entry.ID == *id) {
+            open = append(open, entry)
+        }
+    }
+    if len(open) == 0 {
+        if *id != "" {
+            return fmt.Errorf("no open bet with ID %q in %s", *id, config.LedgerPath)
+        }
+        fmt.Println("No open bets to hedge")
+        return nil
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+    progressf("Fetching NBA stats, live scores and odds...\n")
+    result, err := runPipeline(ctx, client)
+    if err != nil {
+        return err
+    }
+    if degraded := result.degradedSummary(); degraded != "" {
+        progressf("WARNING: degraded sources: %s\n", degraded)
+    }
+    games := make(map[string]Game, len(result.Games))
+    for _, game := range result.Games {
+        games[game.ID] = game
+    }
+
+    plans := []hedgePlan{}
+    for _, entry := range open {
+        game, ok := games[entry.GameID]
+        if !ok {
+            progressf("\n%s  %s: no current odds for %s\n", entry.ID, entry.Team, entry.Game)
+            continue
+        }
+        prob, phase, ok := entryProbability(entry, game, result.Stats, result.LiveScores, result.Schedule, result.PlayerLogs)
+        if !ok {
+            progressf("\n%s  %s: can't price the position (%s)\n", entry.ID, entry.Team, phase)
+            continue
+        }
+        plans = append(plans, planHedge(entry, game, prob, phase, *cashOut))
+    }
+
+    if config.Output == "json" {
+        return printJSON(plans)
+    }
+    for _, plan := range plans {
+        displayHedgePlan(plan)
+    }
+    return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+    "hash/fnv"
+    "math"
+    "math/rand"
+)
+
+// Season sample assumed for teams whose games played are unknown
+const assumedSeasonGames = 20
+
+// calculateConfidence is the probability that team's true win probability
+// beats impliedProb. Both teams' stats are bootstrapped: win rates redrawn
+// from their records, recent games resampled, and ratings and venue
+// margins perturbed by their standard error, and the model is rerun on each draw.
+func calculateConfidence(game Game, team string, teamStats map[string]TeamStats, schedule teamSchedule, liveGame LiveGameState, inPlay bool, impliedProb float64) float64 {
+    opponent := game.HomeTeam
+    if team == game.HomeTeam {
+        opponent = game.AwayTeam
+    }
+
+    // The same draws for every bookmaker keep confidence monotonic in price
+    hash := fnv.New64a()
+    hash.Write([]byte(game.ID + "|" + team))
+    rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+    resampled := make(map[string]TeamStats, len(teamStats))
+    for name, stats := range teamStats {
+        resampled[name] = stats
+    }
+
+    positive := 0
+    for i := 0; i < config.BootstrapSamples; i++ {
+        resampled[team] = resampleTeamStats(teamStats[team], rng)
+        if opponentStats, ok := teamStats[opponent]; ok {
+            resampled[opponent] = resampleTeamStats(opponentStats, rng)
+        }
+        prob, _, _ := winProbability(game, team, resampled, schedule, liveGame, inPlay)
+        if prob > impliedProb {
+            positive++
+        }
+    }
+    return float64(positive) / float64(config.BootstrapSamples)
+}
+
+// resampleTeamStats draws one bootstrap replicate of a team's inputs
+func resampleTeamStats(stats TeamStats, rng *rand.Rand) TeamStats {
+    games := stats.Home.Games + stats.Away.Games
+    if games == 0 {
+        games = assumedSeasonGames
+    }
+    stats.WinRate = resampleRate(stats.WinRate, games, rng)
+    stats.Home.WinRate = resampleRate(stats.Home.WinRate, stats.Home.Games, rng)
+    stats.Away.WinRate = resampleRate(stats.Away.WinRate, stats.Away.Games, rng)
+    stats.Home.NetRating = resampleMargin(stats.Home.NetRating, stats.Home.Games, rng)
+    stats.Away.NetRating = resampleMargin(stats.Away.NetRating, stats.Away.Games, rng)
+
+    if n := len(stats.RecentGames); n > 0 {
+        recent := make([]RecentGame, n)
+        for i := range recent {
+            recent[i] = stats.RecentGames[rng.Intn(n)]
+        }
+        stats.RecentGames = recent
+    }
+
+    if stats.Rating != nil && stats.Rating.Games > 0 && stats.Rating.Pace > 0 {
+        rating := *stats.Rating
+        stdErr := config.MarginStdDev * 100 / rating.Pace / math.Sqrt(float64(rating.Games))
+        rating.Net += rng.NormFloat64() * stdErr
+        stats.Rating = &rating
+    }
+    return stats
+}
+
+// resampleMargin redraws an average scoring margin observed over games
+func resampleMargin(margin float64, games int, rng *rand.Rand) float64 {
+    if games == 0 {
+        return margin
+    }
+    return margin + rng.NormFloat64()*config.MarginStdDev/math.Sqrt(float64(games))
+}
+
+// resampleRate redraws a win rate observed over games
+func resampleRate(rate float64, games int, rng *rand.Rand) float64 {
+    if games == 0 {
+        return rate
+    }
+    wins := 0
+    for i := 0; i < games; i++ {
+        if rng.Float64() < rate {
+            wins++
+        }
+    }
+    return float64(wins) / float64(games)
+}
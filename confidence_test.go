@@ -0,0 +1,126 @@
+package main
+
+import (
+    "math"
+    "math/rand"
+    "testing"
+)
+
+func TestResampleRate(t *testing.T) {
+    tests := []struct {
+        name  string
+        rate  float64
+        games int
+        want  float64
+        tol   float64
+    }{
+        {"no games keeps the rate", 0.63, 0, 0.63, 1e-12},
+        {"unbeaten stays unbeaten", 1, 40, 1, 0},
+        {"winless stays winless", 0, 40, 0, 0},
+        {"averages to the rate", 0.6, 50, 0.6, 0.01},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rng := rand.New(rand.NewSource(1))
+            const draws = 2000
+            sum := 0.0
+            for i := 0; i < draws; i++ {
+                sum += resampleRate(tt.rate, tt.games, rng)
+            }
+            if mean := sum / draws; math.Abs(mean-tt.want) > tt.tol {
+                t.Errorf("mean of %d draws = %.4f, want %.4f", draws, mean, tt.want)
+            }
+        })
+    }
+}
+
+func TestResampleTeamStats(t *testing.T) {
+    withConfig(t, func(c *Config) {})
+    rating := TeamRating{Net: 5, Pace: 100, Games: 25}
+    stats := TeamStats{
+        WinRate: 0.6,
+        Home:    VenueSplit{Games: 20, WinRate: 0.7, NetRating: 6},
+        Away:    VenueSplit{Games: 20, WinRate: 0.5, NetRating: -1},
+        RecentGames: []RecentGame{
+            {Opponent: "Miami Heat", Margin: 10},
+            {Opponent: "Utah Jazz", Margin: -4},
+        },
+        Rating: &rating,
+    }
+
+    rng := rand.New(rand.NewSource(1))
+    const draws = 4000
+    var net, homeNet float64
+    for i := 0; i < draws; i++ {
+        draw := resampleTeamStats(stats, rng)
+        for _, game := range draw.RecentGames {
+            if game.Opponent != "Miami Heat" && game.Opponent != "Utah Jazz" {
+                t.Fatalf("recent game %+v wasn't in the sample", game)
+            }
+        }
+        if len(draw.RecentGames) != 2 {
+            t.Fatalf("%d recent games, want 2", len(draw.RecentGames))
+        }
+        net += draw.Rating.Net
+        homeNet += draw.Home.NetRating
+    }
+    if rating.Net != 5 || stats.Rating != &rating {
+        t.Error("resampleTeamStats changed the input rating")
+    }
+    // The rating's standard error is 12 points a game over 25 games
+    if mean := net / draws; math.Abs(mean-5) > 4*2.4/math.Sqrt(draws) {
+        t.Errorf("mean net = %.3f, want about 5", mean)
+    }
+    if mean := homeNet / draws; math.Abs(mean-6) > 4*config.MarginStdDev/math.Sqrt(20*draws) {
+        t.Errorf("mean home net = %.3f, want about 6", mean)
+    }
+
+    unrated := resampleTeamStats(TeamStats{WinRate: 0.5}, rng)
+    if unrated.Rating != nil || unrated.RecentGames != nil {
+        t.Errorf("unrated draw = %+v, want no rating or recent games", unrated)
+    }
+}
+
+func TestCalculateConfidence(t *testing.T) {
+    withConfig(t, func(c *Config) { c.BootstrapSamples = 400 })
+    game := Game{ID: "g1", HomeTeam: "Boston Celtics", AwayTeam: "Miami Heat"}
+    stats := map[string]TeamStats{
+        "Boston Celtics": {WinRate: 0.65, Home: VenueSplit{Games: 20, WinRate: 0.75}, Away: VenueSplit{Games: 20, WinRate: 0.55}},
+        "Miami Heat":     {WinRate: 0.45, Home: VenueSplit{Games: 20, WinRate: 0.5}, Away: VenueSplit{Games: 20, WinRate: 0.4}},
+    }
+    confidence := func(implied float64) float64 {
+        return calculateConfidence(game, "Boston Celtics", stats, nil, LiveGameState{}, false, implied)
+    }
+
+    tests := []struct {
+        name     string
+        implied  float64
+        min, max float64
+    }{
+        {"a price nothing can lose to", 0, 1, 1},
+        {"a price nothing can beat", 1, 0, 0},
+        {"well under the model", 0.45, 0.9, 1},
+        {"well over the model", 0.85, 0, 0.1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := confidence(tt.implied); got < tt.min || got > tt.max {
+                t.Errorf("confidence at %.2f = %.3f, want %.2f-%.2f", tt.implied, got, tt.min, tt.max)
+            }
+        })
+    }
+
+    // The draws are seeded by game and team, so every bookmaker's price sees
+    // the same replicates and a shorter price never gets more confidence
+    previous := 1.0
+    for implied := 0.40; implied <= 0.90; implied += 0.05 {
+        got := confidence(implied)
+        if got != confidence(implied) {
+            t.Fatalf("confidence at %.2f changed between calls", implied)
+        }
+        if got > previous {
+            t.Errorf("confidence at %.2f = %.3f, above %.3f at a longer price", implied, got, previous)
+        }
+        previous = got
+    }
+}
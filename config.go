@@ -0,0 +1,650 @@
+package main
+
+import (
+    "bufio"
+    "flag"
+    "fmt"
+    "math"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Config holds every tunable of the fetch, model and output stages. Values come
+// from defaultConfig, then the config file's [default] table, then the selected
+// [profile.<name>] table, then NBA_* environment variables, then -set flags.
+type Config struct {
+    Sport      string
+    Regions    string
+    Markets    []string
+    Bookmakers []string
+
+    PeriodMarkets []string
+    PropMarkets   []string
+
+    SeasonWeight        float64
+    RecentWeight        float64
+    HomeCourt           float64
+    HomeCourtPriorGames float64
+    LiveWeight          float64
+    FormDecay           float64
+
+    RatingWeight float64
+    RatingRidge  float64
+    MarginStdDev float64
+    TotalStdDev  float64
+    SimRuns      int
+
+    MinEdge            float64
+    StrongConfidence   float64
+    ModerateConfidence float64
+    LargeEdge          float64
+    BootstrapSamples   int
+    LiveOnly           bool
+
+    Bankroll      float64
+    KellyFraction float64
+    MaxStake      float64
+
+    ExposureRule     string
+    MaxGameExposure  float64
+    MaxTeamExposure  float64
+    MaxDailyExposure float64
+
+    ScheduleAdjustments bool
+    BackToBackPenalty   float64
+    ThreeInFourPenalty  float64
+    RestDayValue        float64
+    TravelPenalty       float64
+    TimeZonePenalty     float64
+
+    InjuryFile             string
+    InjuryURL              string
+    InjuryReplacementLevel float64
+    PointValue             float64
+
+    StaleAfter  time.Duration
+    StaleLag    time.Duration
+    StalePolicy string
+    StaleTier   string
+
+    Timezone    string
+    WindowStart string
+    WindowDays  int
+
+    TopN     int
+    Output   string
+    ShowOdds bool
+    ShowLive bool
+    Explain  bool
+
+    LedgerPath string
+
+    StatsValidation string
+
+    APIKeyFile       string
+    EncryptedKeyFile string
+
+    HTTPTimeout    time.Duration
+    HTTPRetries    int
+    HTTPBackoff    time.Duration
+    HTTPMaxBackoff time.Duration
+
+    FetchParallelism int
+    FetchDeadline    time.Duration
+
+    CacheEnabled   bool
+    CacheDir       string
+    CacheTTLSports time.Duration
+    CacheTTLStats  time.Duration
+    CacheTTLOdds   time.Duration
+    CacheTTLLive   time.Duration
+
+    Alerts AlertConfig
+}
+
+func defaultConfig() *Config {
+    return &Config{
+        Sport:      "basketball_nba",
+        Regions:    "us",
+        Markets:    []string{"h2h", "spreads"},
+        Bookmakers: []string{"betmgm"},
+
+        SeasonWeight:        0.7,
+        RecentWeight:        0.3,
+        HomeCourt:           0.035,
+        HomeCourtPriorGames: 60,
+        LiveWeight:          0.8,
+        FormDecay:           0.85,
+
+        RatingWeight: 0.5,
+        RatingRidge:  5,
+        MarginStdDev: 12,
+        TotalStdDev:  18,
+        SimRuns:      5000,
+
+        StrongConfidence:   0.9,
+        ModerateConfidence: 0.75,
+        LargeEdge:          0.15,
+        BootstrapSamples:   200,
+
+        Bankroll:      1000,
+        KellyFraction: 0.25,
+        MaxStake:      0.05,
+
+        ExposureRule:     "cap",
+        MaxGameExposure:  0.08,
+        MaxTeamExposure:  0.1,
+        MaxDailyExposure: 0.25,
+
+        ScheduleAdjustments: true,
+        BackToBackPenalty:   0.03,
+        ThreeInFourPenalty:  0.015,
+        RestDayValue:        0.01,
+        TravelPenalty:       0.01,
+        TimeZonePenalty:     0.005,
+
+        InjuryReplacementLevel: -8,
+        PointValue:             0.03,
+
+        StaleAfter:  15 * time.Minute,
+        StaleLag:    5 * time.Minute,
+        StalePolicy: "downgrade",
+        StaleTier:   "moderate",
+
+        Timezone: "Local",
+
+        TopN:     5,
+        Output:   "text",
+        ShowOdds: true,
+        ShowLive: true,
+
+        LedgerPath: "bets.jsonl",
+
+        StatsValidation: "warn",
+
+        HTTPTimeout:    10 * time.Second,
+        HTTPRetries:    3,
+        HTTPBackoff:    500 * time.Millisecond,
+        HTTPMaxBackoff: 10 * time.Second,
+
+        FetchParallelism: 3,
+        FetchDeadline:    60 * time.Second,
+
+        CacheEnabled:   true,
+        CacheDir:       ".cache",
+        CacheTTLSports: 24 * time.Hour,
+        CacheTTLStats:  6 * time.Hour,
+        CacheTTLOdds:   60 * time.Second,
+        CacheTTLLive:   15 * time.Second,
+
+        Alerts: AlertConfig{
+            MinEdge:       0.05,
+            MinConfidence: 0.75,
+            Cooldown:      2 * time.Hour,
+            MaxPerHour:    10,
+        },
+    }
+}
+
+// config is the active configuration, loaded once at startup
+var config = defaultConfig()
+
+// fields maps each config key to the field it sets
+func (c *Config) fields() map[string]interface{} {
+    return map[string]interface{}{
+        "sport":      &c.Sport,
+        "regions":    &c.Regions,
+        "markets":    &c.Markets,
+        "bookmakers": &c.Bookmakers,
+
+        "period_markets": &c.PeriodMarkets,
+        "prop_markets":   &c.PropMarkets,
+
+        "season_weight":          &c.SeasonWeight,
+        "recent_weight":          &c.RecentWeight,
+        "home_court":             &c.HomeCourt,
+        "home_court_prior_games": &c.HomeCourtPriorGames,
+        "live_weight":            &c.LiveWeight,
+        "form_decay":             &c.FormDecay,
+
+        "rating_weight": &c.RatingWeight,
+        "rating_ridge":  &c.RatingRidge,
+        "margin_stddev": &c.MarginStdDev,
+        "total_stddev":  &c.TotalStdDev,
+        "sim_runs":      &c.SimRuns,
+
+        "min_edge":            &c.MinEdge,
+        "strong_confidence":   &c.StrongConfidence,
+        "moderate_confidence": &c.ModerateConfidence,
+        "large_edge":          &c.LargeEdge,
+        "bootstrap_samples":   &c.BootstrapSamples,
+        "live_only":           &c.LiveOnly,
+
+        "bankroll":       &c.Bankroll,
+        "kelly_fraction": &c.KellyFraction,
+        "max_stake":      &c.MaxStake,
+
+        "exposure_rule":      &c.ExposureRule,
+        "max_game_exposure":  &c.MaxGameExposure,
+        "max_team_exposure":  &c.MaxTeamExposure,
+        "max_daily_exposure": &c.MaxDailyExposure,
+
+        "schedule_adjustments":  &c.ScheduleAdjustments,
+        "back_to_back_penalty":  &c.BackToBackPenalty,
+        "three_in_four_penalty": &c.ThreeInFourPenalty,
+        "rest_day_value":        &c.RestDayValue,
+        "travel_penalty":        &c.TravelPenalty,
+        "time_zone_penalty":     &c.TimeZonePenalty,
+
+        "injury_file":              &c.InjuryFile,
+        "injury_url":               &c.InjuryURL,
+        "injury_replacement_level": &c.InjuryReplacementLevel,
+        "point_value":              &c.PointValue,
+
+        "stale_after":  &c.StaleAfter,
+        "stale_lag":    &c.StaleLag,
+        "stale_policy": &c.StalePolicy,
+        "stale_tier":   &c.StaleTier,
+
+        "timezone":     &c.Timezone,
+        "window_start": &c.WindowStart,
+        "window_days":  &c.WindowDays,
+
+        "top_n":     &c.TopN,
+        "output":    &c.Output,
+        "show_odds": &c.ShowOdds,
+        "show_live": &c.ShowLive,
+        "explain":   &c.Explain,
+
+        "ledger_path": &c.LedgerPath,
+
+        "stats_validation": &c.StatsValidation,
+
+        "api_key_file":       &c.APIKeyFile,
+        "encrypted_key_file": &c.EncryptedKeyFile,
+
+        "http_timeout":     &c.HTTPTimeout,
+        "http_retries":     &c.HTTPRetries,
+        "http_backoff":     &c.HTTPBackoff,
+        "http_max_backoff": &c.HTTPMaxBackoff,
+
+        "fetch_parallelism": &c.FetchParallelism,
+        "fetch_deadline":    &c.FetchDeadline,
+
+        "cache":            &c.CacheEnabled,
+        "cache_dir":        &c.CacheDir,
+        "cache_ttl_sports": &c.CacheTTLSports,
+        "cache_ttl_stats":  &c.CacheTTLStats,
+        "cache_ttl_odds":   &c.CacheTTLOdds,
+        "cache_ttl_live":   &c.CacheTTLLive,
+
+        "alert_webhook_url":      &c.Alerts.WebhookURL,
+        "alert_slack_url":        &c.Alerts.SlackURL,
+        "alert_discord_url":      &c.Alerts.DiscordURL,
+        "alert_telegram_url":     &c.Alerts.TelegramURL,
+        "alert_telegram_token":   &c.Alerts.TelegramToken,
+        "alert_telegram_chat_id": &c.Alerts.TelegramChatID,
+        "alert_smtp_addr":        &c.Alerts.SMTPAddr,
+        "alert_smtp_user":        &c.Alerts.SMTPUser,
+        "alert_smtp_password":    &c.Alerts.SMTPPassword,
+        "alert_email_from":       &c.Alerts.EmailFrom,
+        "alert_email_to":         &c.Alerts.EmailTo,
+        "alert_min_edge":         &c.Alerts.MinEdge,
+        "alert_min_confidence":   &c.Alerts.MinConfidence,
+        "alert_cooldown":         &c.Alerts.Cooldown,
+        "alert_max_per_hour":     &c.Alerts.MaxPerHour,
+    }
+}
+
+// set parses value into the field named by key
+func (c *Config) set(key, value string) error {
+    field, ok := c.fields()[key]
+    if !ok {
+        return fmt.Errorf("unknown config key %q", key)
+    }
+
+    var err error
+    switch f := field.(type) {
+    case *string:
+        *f = value
+    case *[]string:
+        *f = nil
+        for _, item := range strings.Split(value, ",") {
+            if item = strings.TrimSpace(item); item != "" {
+                *f = append(*f, item)
+            }
+        }
+    case *float64:
+        *f, err = strconv.ParseFloat(value, 64)
+    case *int:
+        *f, err = strconv.Atoi(value)
+    case *bool:
+        *f, err = strconv.ParseBool(value)
+    case *time.Duration:
+        *f, err = time.ParseDuration(value)
+    }
+    if err != nil {
+        return fmt.Errorf("invalid value %q for %s: %v", value, key, err)
+    }
+    return nil
+}
+
+func (c *Config) validate() error {
+    if len(c.Bookmakers) == 0 {
+        return fmt.Errorf("at least one bookmaker is required")
+    }
+    if c.TopN <= 0 {
+        return fmt.Errorf("top_n must be positive")
+    }
+    if err := validatePeriodMarkets(c.Markets, c.PeriodMarkets); err != nil {
+        return err
+    }
+    for _, key := range c.PropMarkets {
+        if _, ok := propStats[key]; !ok {
+            return fmt.Errorf("prop_markets entry %q is not a supported player prop", key)
+        }
+    }
+    if c.SeasonWeight < 0 || c.RecentWeight < 0 || math.Abs(c.SeasonWeight+c.RecentWeight-1) > 1e-9 {
+        return fmt.Errorf("season_weight and recent_weight must not be negative and must add up to 1")
+    }
+    if c.MinEdge < 0 || c.MinEdge >= 1 {
+        return fmt.Errorf("min_edge must be at least 0 and below 1")
+    }
+    if c.Bankroll <= 0 || c.KellyFraction <= 0 || c.KellyFraction > 1 || c.MaxStake <= 0 || c.MaxStake > 1 {
+        return fmt.Errorf("bankroll must be positive, and kelly_fraction and max_stake above 0 and at most 1")
+    }
+    if c.ExposureRule != "cap" && c.ExposureRule != "per_game_max" && c.ExposureRule != "simultaneous" {
+        return fmt.Errorf("exposure_rule must be cap, per_game_max or simultaneous")
+    }
+    if c.MaxGameExposure <= 0 || c.MaxTeamExposure <= 0 || c.MaxDailyExposure <= 0 {
+        return fmt.Errorf("max_game_exposure, max_team_exposure and max_daily_exposure must be positive")
+    }
+    if c.LiveWeight < 0 || c.LiveWeight > 1 {
+        return fmt.Errorf("live_weight must be between 0 and 1")
+    }
+    if c.ModerateConfidence < 0 || c.ModerateConfidence > c.StrongConfidence || c.StrongConfidence > 1 {
+        return fmt.Errorf("confidence thresholds must satisfy 0 <= moderate_confidence <= strong_confidence <= 1")
+    }
+    if c.BootstrapSamples < 20 {
+        return fmt.Errorf("bootstrap_samples must be at least 20")
+    }
+    if c.HomeCourtPriorGames < 0 {
+        return fmt.Errorf("home_court_prior_games must not be negative")
+    }
+    if c.RatingWeight < 0 || c.RatingWeight > 1 {
+        return fmt.Errorf("rating_weight must be between 0 and 1")
+    }
+    if c.RatingRidge < 0 || c.MarginStdDev <= 0 {
+        return fmt.Errorf("rating_ridge must not be negative and margin_stddev must be positive")
+    }
+    if c.TotalStdDev <= 0 || c.SimRuns < 100 {
+        return fmt.Errorf("total_stddev must be positive and sim_runs at least 100")
+    }
+    if c.FormDecay <= 0 || c.FormDecay > 1 {
+        return fmt.Errorf("form_decay must be above 0 and at most 1")
+    }
+    if c.PointValue <= 0 || c.PointValue > 0.1 {
+        return fmt.Errorf("point_value must be above 0 and at most 0.1")
+    }
+    if c.HTTPTimeout <= 0 || c.HTTPBackoff <= 0 || c.HTTPMaxBackoff < c.HTTPBackoff {
+        return fmt.Errorf("http_timeout and http_backoff must be positive and http_max_backoff at least http_backoff")
+    }
+    if c.FetchParallelism <= 0 || c.FetchDeadline <= 0 {
+        return fmt.Errorf("fetch_parallelism and fetch_deadline must be positive")
+    }
+    if c.HTTPRetries < 0 {
+        return fmt.Errorf("http_retries must not be negative")
+    }
+    if c.BackToBackPenalty < 0 || c.ThreeInFourPenalty < 0 || c.RestDayValue < 0 || c.TravelPenalty < 0 || c.TimeZonePenalty < 0 {
+        return fmt.Errorf("schedule adjustment weights must not be negative")
+    }
+    if c.StalePolicy != "downgrade" && c.StalePolicy != "exclude" {
+        return fmt.Errorf("stale_policy must be downgrade or exclude, got %q", c.StalePolicy)
+    }
+    if c.StaleTier != "moderate" && c.StaleTier != "speculative" {
+        return fmt.Errorf("stale_tier must be moderate or speculative, got %q", c.StaleTier)
+    }
+    if c.Timezone != "Local" {
+        if _, err := time.LoadLocation(c.Timezone); err != nil {
+            return fmt.Errorf("unknown timezone %q", c.Timezone)
+        }
+    }
+    if c.WindowDays < 0 {
+        return fmt.Errorf("window_days must not be negative")
+    }
+    switch c.StatsValidation {
+    case "strict", "warn", "off":
+    default:
+        return fmt.Errorf("stats_validation must be strict, warn or off, got %q", c.StatsValidation)
+    }
+    if c.Output != "text" && c.Output != "json" {
+        return fmt.Errorf("output must be text or json, got %q", c.Output)
+    }
+    return nil
+}
+
+// parseConfigFile reads the subset of TOML used by config files: [tables],
+// key = value pairs with string, number and boolean values, single-line
+// arrays, and # comments. Values are returned as strings ready for set.
+func parseConfigFile(path string) (map[string]map[string]string, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("error reading config file: %v", err)
+    }
+    defer file.Close()
+
+    tables := map[string]map[string]string{"": {}}
+    table := ""
+    scanner := bufio.NewScanner(file)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(stripComment(scanner.Text()))
+        if line == "" {
+            continue
+        }
+
+        if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+            table = strings.TrimSpace(line[1 : len(line)-1])
+            if _, exists := tables[table]; !exists {
+                tables[table] = map[string]string{}
+            }
+            continue
+        }
+
+        eq := strings.Index(line, "=")
+        if eq < 0 {
+            return nil, fmt.Errorf("%s:%d: expected key = value", path, lineNo)
+        }
+        key := strings.TrimSpace(line[:eq])
+        value, err := parseConfigValue(strings.TrimSpace(line[eq+1:]))
+        if err != nil {
+            return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+        }
+        tables[table][key] = value
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("error reading config file: %v", err)
+    }
+
+    return tables, nil
+}
+
+func stripComment(line string) string {
+    inQuote := rune(0)
+    for i, r := range line {
+        switch {
+        case inQuote != 0 && r == inQuote:
+            inQuote = 0
+        case inQuote == 0 && (r == '"' || r == '\''):
+            inQuote = r
+        case inQuote == 0 && r == '#':
+            return line[:i]
+        }
+    }
+    return line
+}
+
+func parseConfigValue(raw string) (string, error) {
+    if strings.HasPrefix(raw, "[") {
+        if !strings.HasSuffix(raw, "]") {
+            return "", fmt.Errorf("arrays must be on a single line")
+        }
+        var items []string
+        for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+            if item = strings.TrimSpace(item); item == "" {
+                continue
+            }
+            value, err := parseConfigValue(item)
+            if err != nil {
+                return "", err
+            }
+            items = append(items, value)
+        }
+        return strings.Join(items, ","), nil
+    }
+
+    if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') {
+        if raw[len(raw)-1] != raw[0] {
+            return "", fmt.Errorf("unterminated string %s", raw)
+        }
+        if raw[0] == '"' {
+            return strconv.Unquote(raw)
+        }
+        return raw[1 : len(raw)-1], nil
+    }
+
+    if raw == "" {
+        return "", fmt.Errorf("missing value")
+    }
+    return raw, nil
+}
+
+func (c *Config) applyTable(table map[string]string, name string) error {
+    keys := make([]string, 0, len(table))
+    for key := range table {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    for _, key := range keys {
+        if err := c.set(key, table[key]); err != nil {
+            return fmt.Errorf("[%s] %v", name, err)
+        }
+    }
+    return nil
+}
+
+// applyEnv overrides any key with a matching NBA_<KEY> environment variable
+func (c *Config) applyEnv() error {
+    for key := range c.fields() {
+        if value, ok := os.LookupEnv("NBA_" + strings.ToUpper(key)); ok {
+            if err := c.set(key, value); err != nil {
+                return fmt.Errorf("NBA_%s: %v", strings.ToUpper(key), err)
+            }
+        }
+    }
+    return nil
+}
+
+func loadConfig(path, profile string, overrides []string) (*Config, error) {
+    c := defaultConfig()
+
+    if path != "" {
+        tables, err := parseConfigFile(path)
+        if err != nil {
+            return nil, err
+        }
+        if err := c.applyTable(tables[""], "top level"); err != nil {
+            return nil, err
+        }
+        if table, ok := tables["default"]; ok {
+            if err := c.applyTable(table, "default"); err != nil {
+                return nil, err
+            }
+        }
+        if profile != "" {
+            table, ok := tables["profile."+profile]
+            if !ok {
+                return nil, fmt.Errorf("profile %q not found in %s", profile, path)
+            }
+            if err := c.applyTable(table, "profile."+profile); err != nil {
+                return nil, err
+            }
+        }
+    } else if profile != "" {
+        return nil, fmt.Errorf("profile %q requested but no config file found", profile)
+    }
+
+    if err := c.applyEnv(); err != nil {
+        return nil, err
+    }
+
+    for _, override := range overrides {
+        eq := strings.Index(override, "=")
+        if eq < 0 {
+            return nil, fmt.Errorf("-set expects key=value, got %q", override)
+        }
+        if err := c.set(strings.TrimSpace(override[:eq]), strings.TrimSpace(override[eq+1:])); err != nil {
+            return nil, err
+        }
+    }
+
+    if err := c.validate(); err != nil {
+        return nil, err
+    }
+
+    // Alert credentials must never show up in logs either
+    registerSecret(c.Alerts.TelegramToken)
+    registerSecret(c.Alerts.SMTPPassword)
+    registerSecret(c.Alerts.WebhookURL)
+    registerSecret(c.Alerts.SlackURL)
+    registerSecret(c.Alerts.DiscordURL)
+    return c, nil
+}
+
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// configFlags are the -config, -profile and -set flags shared by every command
+type configFlags struct {
+    path      *string
+    profile   *string
+    overrides stringList
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+    defaultPath := os.Getenv("NBA_CONFIG")
+    if defaultPath == "" {
+        if _, err := os.Stat("nba.toml"); err == nil {
+            defaultPath = "nba.toml"
+        }
+    }
+
+    f := &configFlags{
+        path:    fs.String("config", defaultPath, "config file (default $NBA_CONFIG or ./nba.toml)"),
+        profile: fs.String("profile", os.Getenv("NBA_PROFILE"), "named profile from the config file"),
+    }
+    fs.Var(&f.overrides, "set", "override a config key, e.g. -set top_n=10 (repeatable)")
+    return f
+}
+
+// load resolves the configuration and installs it as the active config
+func (f *configFlags) load() error {
+    loaded, err := loadConfig(*f.path, *f.profile, f.overrides)
+    if err != nil {
+        return err
+    }
+    config = loaded
+
+    cache = nil
+    if config.CacheEnabled {
+        cache = newResponseCache(config.CacheDir)
+    }
+    return nil
+}
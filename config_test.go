@@ -0,0 +1,167 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// writeConfigFile saves contents as a config file in a temp dir
+func writeConfigFile(t *testing.T, contents string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "nba.toml")
+    if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestParseConfigValue(t *testing.T) {
+    tests := []struct {
+        raw, want, err string
+    }{
+        {raw: "42", want: "42"},
+        {raw: "true", want: "true"},
+        {raw: `"us,eu"`, want: "us,eu"},
+        {raw: `"tab\there"`, want: "tab\there"},
+        {raw: `'C:\odds'`, want: `C:\odds`},
+        {raw: `["fanduel", 'draftkings', betmgm]`, want: "fanduel,draftkings,betmgm"},
+        {raw: "[]", want: ""},
+        {raw: `["fanduel"`, err: "single line"},
+        {raw: `"open`, err: "unterminated string"},
+        {raw: "", err: "missing value"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.raw, func(t *testing.T) {
+            got, err := parseConfigValue(tt.raw)
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Errorf("parseConfigValue = %q, %v, want an error containing %q", got, err, tt.err)
+                }
+                return
+            }
+            if err != nil || got != tt.want {
+                t.Errorf("parseConfigValue = %q, %v, want %q", got, err, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseConfigFile(t *testing.T) {
+    path := writeConfigFile(t, `# odds settings
+regions = "us"   # trailing comment
+cache_dir = "odds#cache"
+
+[default]
+bookmakers = ["fanduel", "draftkings"]
+
+[ profile.live ]
+live_only = true
+`)
+    got, err := parseConfigFile(path)
+    if err != nil {
+        t.Fatalf("parseConfigFile: %v", err)
+    }
+    want := map[string]map[string]string{
+        "":             {"regions": "us", "cache_dir": "odds#cache"},
+        "default":      {"bookmakers": "fanduel,draftkings"},
+        "profile.live": {"live_only": "true"},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("tables = %v, want %v", got, want)
+    }
+
+    bad := writeConfigFile(t, "top_n = 5\njust a line\n")
+    if _, err := parseConfigFile(bad); err == nil || !strings.Contains(err.Error(), ":2: expected key = value") {
+        t.Errorf("parseConfigFile = %v, want a line 2 error", err)
+    }
+}
+
+// Each key is set at every layer up to the one that should win, so a layer
+// applied out of order shows up as the wrong value
+func TestLoadConfigOverrideOrder(t *testing.T) {
+    path := writeConfigFile(t, `
+top_n = 1
+min_edge = 0.01
+kelly_fraction = 0.1
+max_stake = 0.02
+bankroll = 2000
+
+[default]
+min_edge = 0.02
+kelly_fraction = 0.2
+max_stake = 0.03
+bankroll = 3000
+
+[profile.sharp]
+kelly_fraction = 0.3
+max_stake = 0.04
+bankroll = 4000
+
+[profile.other]
+bankroll = 9999
+`)
+    t.Setenv("NBA_MAX_STAKE", "0.05")
+    t.Setenv("NBA_BANKROLL", "5000")
+
+    c, err := loadConfig(path, "sharp", []string{"bankroll = 6000", "stale_after=30m"})
+    if err != nil {
+        t.Fatalf("loadConfig: %v", err)
+    }
+    got := []float64{float64(c.TopN), c.MinEdge, c.KellyFraction, c.MaxStake, c.Bankroll}
+    want := []float64{1, 0.02, 0.3, 0.05, 6000}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("top_n, min_edge, kelly_fraction, max_stake, bankroll = %v, want %v", got, want)
+    }
+    if c.StaleAfter != 30*time.Minute {
+        t.Errorf("stale_after = %s, want 30m", c.StaleAfter)
+    }
+    if c.SeasonWeight != defaultConfig().SeasonWeight {
+        t.Errorf("season_weight = %v, want the default", c.SeasonWeight)
+    }
+
+    // Without a profile the [default] table is as far as the file goes
+    c, err = loadConfig(path, "", nil)
+    if err != nil {
+        t.Fatalf("loadConfig without a profile: %v", err)
+    }
+    if c.KellyFraction != 0.2 || c.MaxStake != 0.05 || c.Bankroll != 5000 {
+        t.Errorf("kelly_fraction, max_stake, bankroll = %v, %v, %v, want 0.2, 0.05, 5000", c.KellyFraction, c.MaxStake, c.Bankroll)
+    }
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+    path := writeConfigFile(t, "[profile.sharp]\nkelly_fraction = 0.3\n")
+    tests := []struct {
+        name      string
+        path      string
+        profile   string
+        env       map[string]string
+        overrides []string
+        want      string
+    }{
+        {name: "unknown profile", path: path, profile: "loose", want: `profile "loose" not found`},
+        {name: "profile without a file", profile: "sharp", want: "no config file found"},
+        {name: "unknown key", overrides: []string{"top_m=3"}, want: `unknown config key "top_m"`},
+        {name: "override without a value", overrides: []string{"top_n"}, want: "-set expects key=value"},
+        {name: "bad env value", env: map[string]string{"NBA_TOP_N": "many"}, want: "NBA_TOP_N"},
+        {name: "weights that don't add up", overrides: []string{"season_weight=0.8"}, want: "must add up to 1"},
+        {name: "min_edge out of range", overrides: []string{"min_edge=1"}, want: "min_edge"},
+        {name: "kelly_fraction out of range", overrides: []string{"kelly_fraction=1.5"}, want: "kelly_fraction"},
+        {name: "override applied after the profile", path: path, profile: "sharp", overrides: []string{"kelly_fraction=2"}, want: "kelly_fraction"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            for key, value := range tt.env {
+                t.Setenv(key, value)
+            }
+            _, err := loadConfig(tt.path, tt.profile, tt.overrides)
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Errorf("loadConfig = %v, want an error containing %q", err, tt.want)
+            }
+        })
+    }
+}
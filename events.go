@@ -0,0 +1,201 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+const (
+    eventValueBetNew     = "value_bet.new"
+    eventValueBetChanged = "value_bet.changed"
+    eventValueBetRemoved = "value_bet.removed"
+    eventLiveGame        = "live_game.changed"
+)
+
+// Edge moves smaller than this are not worth a value_bet.changed event
+const minEdgeChange = 0.005
+
+type streamEvent struct {
+    ID   uint64      `json:"id"`
+    Type string      `json:"type"`
+    Time time.Time   `json:"time"`
+    Data interface{} `json:"data"`
+}
+
+// eventLog is a bounded, append-only log of stream events. Event IDs only grow,
+// so clients can resume from the last ID they saw. They start from the clock
+// rather than 1, so a cursor from before a restart is always behind the new
+// log and gets a reset instead of silently skipping events.
+type eventLog struct {
+    mu      sync.Mutex
+    events  []streamEvent
+    nextID  uint64
+    max     int
+    waiters map[chan struct{}]struct{}
+}
+
+func newEventLog(max int) *eventLog {
+    return &eventLog{
+        nextID:  uint64(time.Now().UnixMicro()),
+        max:     max,
+        waiters: make(map[chan struct{}]struct{}),
+    }
+}
+
+func (l *eventLog) publish(typ string, data interface{}) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    l.events = append(l.events, streamEvent{
+        ID:   l.nextID,
+        Type: typ,
+        Time: time.Now(),
+        Data: data,
+    })
+    l.nextID++
+    if len(l.events) > l.max {
+        l.events = l.events[len(l.events)-l.max:]
+    }
+
+    for ch := range l.waiters {
+        select {
+        case ch <- struct{}{}:
+        default:
+        }
+    }
+}
+
+// since returns every event after cursor. ok is false when events after the
+// cursor have already been dropped from the log, or when the cursor is from
+// before a restart and ahead of anything published since, and the client
+// must resync.
+func (l *eventLog) since(cursor uint64) (events []streamEvent, ok bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if cursor >= l.nextID || len(l.events) > 0 && cursor+1 < l.events[0].ID {
+        return append([]streamEvent(nil), l.events...), false
+    }
+    for _, ev := range l.events {
+        if ev.ID > cursor {
+            events = append(events, ev)
+        }
+    }
+    return events, true
+}
+
+// subscribe returns a channel that is signalled whenever a new event is published
+func (l *eventLog) subscribe() (<-chan struct{}, func()) {
+    ch := make(chan struct{}, 1)
+    l.mu.Lock()
+    l.waiters[ch] = struct{}{}
+    l.mu.Unlock()
+
+    return ch, func() {
+        l.mu.Lock()
+        delete(l.waiters, ch)
+        l.mu.Unlock()
+    }
+}
+
+func valueBetKey(bet ValueBet) string {
+    return fmt.Sprintf("%s|%s|%s|%s", bet.GameID, bet.Bookmaker, bet.Market, bet.Team)
+}
+
+// valueBetChange is the payload of a value_bet.changed event
+type valueBetChange struct {
+    Previous ValueBet `json:"previous"`
+    Current  ValueBet `json:"current"`
+}
+
+// publishDiff emits events for every difference between two snapshots
+func publishDiff(l *eventLog, prevBets, bets map[string]ValueBet, prevLive, live map[string]LiveGameState) {
+    for key, bet := range bets {
+        prev, existed := prevBets[key]
+        if !existed {
+            l.publish(eventValueBetNew, bet)
+        } else if prev.Odds != bet.Odds || math.Abs(prev.Value-bet.Value) >= minEdgeChange {
+            l.publish(eventValueBetChanged, valueBetChange{Previous: prev, Current: bet})
+        }
+    }
+    for key, prev := range prevBets {
+        if _, exists := bets[key]; !exists {
+            l.publish(eventValueBetRemoved, prev)
+        }
+    }
+
+    for gameKey, liveGame := range live {
+        if prev, existed := prevLive[gameKey]; !existed || prev != liveGame {
+            l.publish(eventLiveGame, liveGame)
+        }
+    }
+}
+
+// handleEvents streams events as Server-Sent Events. Clients resume with the
+// standard Last-Event-ID header or a ?cursor= query parameter.
+func (a *apiServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        writeError(w, http.StatusInternalServerError, "streaming not supported")
+        return
+    }
+
+    var cursor uint64
+    rawCursor := r.Header.Get("Last-Event-ID")
+    if rawCursor == "" {
+        rawCursor = r.URL.Query().Get("cursor")
+    }
+    if rawCursor != "" {
+        parsed, err := strconv.ParseUint(rawCursor, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid cursor %q", rawCursor))
+            return
+        }
+        cursor = parsed
+    }
+
+    notify, unsubscribe := a.store.events.subscribe()
+    defer unsubscribe()
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(15 * time.Second)
+    defer heartbeat.Stop()
+
+    for {
+        events, complete := a.store.events.since(cursor)
+        if !complete && cursor > 0 {
+            // The client missed events that were already evicted; tell it to
+            // refetch the REST endpoints before applying the rest of the stream
+            fmt.Fprintf(w, "event: reset\ndata: {\"cursor\":%d}\n\n", cursor)
+            cursor = 0
+        }
+        for _, ev := range events {
+            data, err := json.Marshal(ev.Data)
+            if err != nil {
+                continue
+            }
+            fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+            cursor = ev.ID
+        }
+        flusher.Flush()
+
+        select {
+        case <-r.Context().Done():
+            return
+        case <-notify:
+        case <-heartbeat.C:
+            fmt.Fprint(w, ": ping\n\n")
+            flusher.Flush()
+        }
+    }
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "math"
+    "time"
+)
+
+// exposureKey is one thing a bankroll can be overexposed to
+type exposureKey struct {
+    kind, name string
+}
+
+// exposureBook tracks stake already committed to each game, team and day
+type exposureBook struct {
+    staked map[exposureKey]float64
+}
+
+func newExposureBook() *exposureBook {
+    return &exposureBook{staked: make(map[exposureKey]float64)}
+}
+
+// positionKeys are the game, team and day a position counts toward. Totals
+// back no team; team totals and moneylines and spreads back the team named.
+func positionKeys(gameID, marketKey, selection string, point float64, commence time.Time) []exposureKey {
+    keys := []exposureKey{
+        {"game", gameID},
+        {"day", commence.In(displayLocation()).Format("2006-01-02")},
+    }
+    if _, isProp := propStats[marketKey]; isProp {
+        return keys
+    }
+    outcome := betOutcome(ValueBet{Market: marketKey, Team: selection, Point: point})
+    switch base, _ := splitMarketKey(marketKey); base {
+    case "h2h", "spreads":
+        keys = append(keys, exposureKey{"team", canonicalTeamName(outcome.Name)})
+    case "team_totals":
+        keys = append(keys, exposureKey{"team", canonicalTeamName(outcome.Description)})
+    }
+    return keys
+}
+
+// parlayKeys are the keys any leg of a parlay counts toward, each once: the
+// whole stake is lost if any leg loses
+func parlayKeys(legs []ValueBet) []exposureKey {
+    var keys []exposureKey
+    seen := make(map[exposureKey]bool)
+    for _, leg := range legs {
+        for _, key := range positionKeys(leg.GameID, leg.Market, leg.Team, leg.Point, leg.CommenceTime) {
+            if !seen[key] {
+                seen[key] = true
+                keys = append(keys, key)
+            }
+        }
+    }
+    return keys
+}
+
+// entryCommence is when an entry's game tips off, or when it was placed for
+// entries recorded before tip-off times were kept
+func entryCommence(entry LedgerEntry) time.Time {
+    if entry.CommenceTime.IsZero() {
+        return entry.PlacedAt
+    }
+    return entry.CommenceTime
+}
+
+// addOpen commits the stakes of open ledger bets
+func (b *exposureBook) addOpen(open []LedgerEntry) {
+    for _, entry := range open {
+        b.add(positionKeys(entry.GameID, entry.Market, entry.Team, entry.Point, entryCommence(entry)), entry.Stake)
+    }
+}
+
+// add commits stake to every key
+func (b *exposureBook) add(keys []exposureKey, stake float64) {
+    for _, key := range keys {
+        b.staked[key] += stake
+    }
+}
+
+// room is the most that can still go on keys before a cap is hit, and the
+// kind of cap that binds
+func (b *exposureBook) room(keys []exposureKey) (float64, string) {
+    room, binding := math.Inf(1), ""
+    for _, key := range keys {
+        if left := exposureCap(key.kind) - b.staked[key]; left < room {
+            room, binding = math.Max(0, left), key.kind
+        }
+    }
+    return room, binding
+}
+
+// exposureCap is the most of the bankroll allowed on one key of kind
+func exposureCap(kind string) float64 {
+    switch kind {
+    case "game":
+        return config.MaxGameExposure * config.Bankroll
+    case "team":
+        return config.MaxTeamExposure * config.Bankroll
+    case "day":
+        return config.MaxDailyExposure * config.Bankroll
+    }
+    return math.Inf(1)
+}
+
+// applyExposure trims the suggested stakes of valueBets, best first, so that
+// together with the open bets in the ledger no game, team or day carries
+// more than its cap. Under the per_game_max rule, bets on one game are
+// also scaled down together so they stake no more in total than the
+// largest of them would alone. Under the simultaneous rule, bets on one
+// game are sized by joint Kelly over the game's simulated runs, which
+// pricer supplies.
+func applyExposure(valueBets []ValueBet, open []LedgerEntry, pricer *parlayPricer) {
+    book := newExposureBook()
+    book.addOpen(open)
+
+    if config.ExposureRule == "simultaneous" && pricer != nil {
+        simultaneousKelly(valueBets, pricer)
+    }
+
+    if config.ExposureRule == "per_game_max" {
+        largest := make(map[string]float64)
+        total := make(map[string]float64)
+        for _, bet := range valueBets {
+            largest[bet.GameID] = math.Max(largest[bet.GameID], bet.Stake)
+            total[bet.GameID] += bet.Stake
+        }
+        for i := range valueBets {
+            bet := &valueBets[i]
+            if total[bet.GameID] > largest[bet.GameID] {
+                bet.Stake, bet.StakeNote = trimStake(bet.Stake, bet.Stake*largest[bet.GameID]/total[bet.GameID],
+                    bet.StakeNote, "shared with other bets on this game")
+            }
+        }
+    }
+
+    for i := range valueBets {
+        bet := &valueBets[i]
+        keys := positionKeys(bet.GameID, bet.Market, bet.Team, bet.Point, bet.CommenceTime)
+        if room, binding := book.room(keys); bet.Stake > room {
+            bet.Stake, bet.StakeNote = trimStake(bet.Stake, room, bet.StakeNote, binding+" exposure limit")
+        }
+        book.add(keys, bet.Stake)
+    }
+}
+
+// simultaneousKelly trims the stakes of bets on one game to what Kelly
+// stakes on them placed together. Player props aren't simulated and keep
+// their own stakes.
+func simultaneousKelly(valueBets []ValueBet, pricer *parlayPricer) {
+    byGame := make(map[string][]int)
+    var gameIDs []string
+    for i, bet := range valueBets {
+        if _, isProp := propStats[bet.Market]; isProp {
+            continue
+        }
+        if byGame[bet.GameID] == nil {
+            gameIDs = append(gameIDs, bet.GameID)
+        }
+        byGame[bet.GameID] = append(byGame[bet.GameID], i)
+    }
+
+    for _, gameID := range gameIDs {
+        indexes := byGame[gameID]
+        if len(indexes) < 2 {
+            continue
+        }
+        game, sim := pricer.sim(gameID)
+        if sim == nil {
+            continue
+        }
+        settlers := make([]func(run simRun) float64, len(indexes))
+        payouts := make([]float64, len(indexes))
+        ok := true
+        for j, i := range indexes {
+            settlers[j], ok = sim.settler(game, valueBets[i].Market, betOutcome(valueBets[i]), 0, 0)
+            if !ok {
+                break
+            }
+            payouts[j] = americanToDecimal(valueBets[i].Odds) - 1
+        }
+        if !ok {
+            continue
+        }
+
+        returns := make([][]float64, len(sim.Runs))
+        for r, run := range sim.Runs {
+            returns[r] = make([]float64, len(indexes))
+            for j, settle := range settlers {
+                switch result := settle(run); {
+                case result > 0:
+                    returns[r][j] = payouts[j]
+                case result < 0:
+                    returns[r][j] = -1
+                }
+            }
+        }
+
+        for j, fraction := range jointKelly(returns) {
+            bet := &valueBets[indexes[j]]
+            stake := math.Min(fraction*config.KellyFraction, config.MaxStake) * config.Bankroll
+            bet.Stake, bet.StakeNote = trimStake(bet.Stake, stake, bet.StakeNote, "Kelly together with the other bets on this game")
+        }
+    }
+}
+
+// jointKelly is the bankroll fractions that maximize expected log growth
+// from placing every bet at once, given each bet's return per unit staked
+// in equally likely scenarios. Coordinate-wise Newton steps keep every
+// fraction non-negative and the bankroll positive in every scenario.
+func jointKelly(returns [][]float64) []float64 {
+    if len(returns) == 0 {
+        return nil
+    }
+    fractions := make([]float64, len(returns[0]))
+    wealth := make([]float64, len(returns))
+    for s := range wealth {
+        wealth[s] = 1
+    }
+
+    for sweep := 0; sweep < 100; sweep++ {
+        moved := 0.0
+        for j := range fractions {
+            var grad, curv float64
+            for s, r := range returns {
+                x := r[j] / wealth[s]
+                grad += x
+                curv += x * x
+            }
+            if curv == 0 {
+                continue
+            }
+            step := math.Max(grad/curv, -fractions[j])
+            // Halve the step until every scenario keeps some bankroll
+            for math.Abs(step) > 1e-9 && !solvent(returns, wealth, j, step) {
+                step /= 2
+            }
+            if math.Abs(step) <= 1e-9 {
+                continue
+            }
+            fractions[j] += step
+            for s, r := range returns {
+                wealth[s] += step * r[j]
+            }
+            moved = math.Max(moved, math.Abs(step))
+        }
+        if moved < 1e-6 {
+            break
+        }
+    }
+    return fractions
+}
+
+// solvent reports whether moving bet j's fraction by step leaves bankroll
+// in every scenario
+func solvent(returns [][]float64, wealth []float64, j int, step float64) bool {
+    for s, r := range returns {
+        if wealth[s]+step*r[j] <= 0 {
+            return false
+        }
+    }
+    return true
+}
+
+// trimStake lowers stake to limit if it's above it, adding why to note
+func trimStake(stake, limit float64, note, reason string) (float64, string) {
+    limit = roundStake(limit)
+    if limit >= stake {
+        return stake, note
+    }
+    if note != "" {
+        return limit, note + ", then " + reason
+    }
+    return limit, fmt.Sprintf("trimmed from %.2f, %s", stake, reason)
+}
+
+// openLedgerEntries is every open bet in the ledger, or none if it can't be
+// read; exposure then counts only the bets being proposed
+func openLedgerEntries() []LedgerEntry {
+    entries, err := loadLedger(config.LedgerPath)
+    if err != nil {
+        log.Printf("WARNING: exposure: %v", err)
+        return nil
+    }
+    var open []LedgerEntry
+    for _, entry := range entries {
+        if entry.Status == betOpen {
+            open = append(open, entry)
+        }
+    }
+    return open
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+    "math"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// withConfig runs a test against the default config changed by edit
+func withConfig(t *testing.T, edit func(c *Config)) {
+    t.Helper()
+    saved := config
+    c := *defaultConfig()
+    edit(&c)
+    config = &c
+    t.Cleanup(func() { config = saved })
+}
+
+var (
+    exposureDay  = time.Date(2025, 1, 10, 18, 0, 0, 0, time.UTC)
+    exposureNext = exposureDay.Add(24 * time.Hour)
+)
+
+func exposureBet(gameID, market, team string, point, stake float64, commence time.Time) ValueBet {
+    return ValueBet{GameID: gameID, Market: market, Team: team, Point: point, Stake: stake, CommenceTime: commence}
+}
+
+func TestApplyExposure(t *testing.T) {
+    tests := []struct {
+        name   string
+        edit   func(c *Config)
+        open   []LedgerEntry
+        bets   []ValueBet
+        stakes []float64
+        notes  []string
+    }{
+        {
+            name: "open ledger bet uses up the game cap",
+            open: []LedgerEntry{{GameID: "g1", Market: "h2h", Team: "Boston Celtics", Stake: 70, CommenceTime: exposureDay}},
+            bets: []ValueBet{
+                exposureBet("g1", "totals", "Over", 221.5, 30, exposureDay),
+                exposureBet("g2", "totals", "Over", 215.5, 30, exposureDay),
+            },
+            stakes: []float64{10, 30},
+            notes:  []string{"game exposure limit", ""},
+        },
+        {
+            name: "team cap across markets",
+            edit: func(c *Config) { c.MaxGameExposure = 1 },
+            bets: []ValueBet{
+                exposureBet("g1", "h2h", "Boston Celtics", 0, 50, exposureDay),
+                exposureBet("g1", "spreads", "Boston Celtics", -4.5, 40, exposureDay),
+                exposureBet("g1", "team_totals", "Boston Celtics Over", 112.5, 30, exposureDay),
+                exposureBet("g1", "totals", "Over", 221.5, 30, exposureDay),
+            },
+            stakes: []float64{50, 40, 10, 30},
+            notes:  []string{"", "", "team exposure limit", ""},
+        },
+        {
+            name: "day cap across games",
+            bets: []ValueBet{
+                exposureBet("g1", "totals", "Over", 221.5, 70, exposureDay),
+                exposureBet("g2", "totals", "Over", 215.5, 70, exposureDay),
+                exposureBet("g3", "totals", "Under", 230.5, 70, exposureDay),
+                exposureBet("g4", "totals", "Under", 210.5, 70, exposureDay),
+                exposureBet("g5", "totals", "Over", 220.5, 70, exposureNext),
+            },
+            stakes: []float64{70, 70, 70, 40, 70},
+            notes:  []string{"", "", "", "day exposure limit", ""},
+        },
+        {
+            name: "per_game_max scales a game's bets to the largest",
+            edit: func(c *Config) { c.ExposureRule = "per_game_max" },
+            bets: []ValueBet{
+                exposureBet("g1", "h2h", "Boston Celtics", 0, 40, exposureDay),
+                exposureBet("g1", "totals", "Over", 221.5, 20, exposureDay),
+                exposureBet("g1", "spreads", "Boston Celtics", -4.5, 20, exposureDay),
+                exposureBet("g2", "h2h", "Miami Heat", 0, 30, exposureDay),
+            },
+            stakes: []float64{20, 10, 10, 30},
+            notes:  []string{"shared with other bets", "shared with other bets", "shared with other bets", ""},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            withConfig(t, func(c *Config) {
+                if tt.edit != nil {
+                    tt.edit(c)
+                }
+            })
+            applyExposure(tt.bets, tt.open, nil)
+            for i, bet := range tt.bets {
+                if bet.Stake != tt.stakes[i] {
+                    t.Errorf("bet %d stake = %.2f, want %.2f", i, bet.Stake, tt.stakes[i])
+                }
+                if tt.notes[i] == "" && bet.StakeNote != "" || !strings.Contains(bet.StakeNote, tt.notes[i]) {
+                    t.Errorf("bet %d note = %q, want %q", i, bet.StakeNote, tt.notes[i])
+                }
+            }
+        })
+    }
+}
+
+func TestTrimStake(t *testing.T) {
+    tests := []struct {
+        name         string
+        stake, limit float64
+        note, reason string
+        wantStake    float64
+        wantNote     string
+    }{
+        {"under the limit", 20, 30, "", "game exposure limit", 20, ""},
+        {"over the limit", 30, 12.345, "", "game exposure limit", 12.35, "trimmed from 30.00, game exposure limit"},
+        {"trimmed twice", 12.35, 5, "trimmed from 30.00, game exposure limit", "day exposure limit", 5,
+            "trimmed from 30.00, game exposure limit, then day exposure limit"},
+        {"to nothing", 10, -3, "", "team exposure limit", -3, "trimmed from 10.00, team exposure limit"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            stake, note := trimStake(tt.stake, tt.limit, tt.note, tt.reason)
+            if stake != tt.wantStake || note != tt.wantNote {
+                t.Errorf("trimStake = %.2f, %q, want %.2f, %q", stake, note, tt.wantStake, tt.wantNote)
+            }
+        })
+    }
+}
+
+func TestPositionKeys(t *testing.T) {
+    day := exposureKey{"day", exposureDay.In(displayLocation()).Format("2006-01-02")}
+    tests := []struct {
+        market, selection string
+        want              []exposureKey
+    }{
+        {"h2h", "Boston Celtics", []exposureKey{{"game", "g1"}, day, {"team", "Boston Celtics"}}},
+        {"spreads_h1", "Boston Celtics", []exposureKey{{"game", "g1"}, day, {"team", "Boston Celtics"}}},
+        {"team_totals", "Boston Celtics Over", []exposureKey{{"game", "g1"}, day, {"team", "Boston Celtics"}}},
+        {"totals", "Over", []exposureKey{{"game", "g1"}, day}},
+        {"player_points", "Jayson Tatum Over", []exposureKey{{"game", "g1"}, day}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.market, func(t *testing.T) {
+            got := positionKeys("g1", tt.market, tt.selection, 0, exposureDay)
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("positionKeys = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestJointKelly(t *testing.T) {
+    // Ten equally likely scenarios of an even-money bet that wins six
+    single := make([][]float64, 10)
+    correlated := make([][]float64, 10)
+    for s := range single {
+        r := -1.0
+        if s < 6 {
+            r = 1
+        }
+        single[s] = []float64{r}
+        correlated[s] = []float64{r, r}
+    }
+    // Two such bets, independent: every pairing of five scenarios each
+    var independent [][]float64
+    for a := 0; a < 5; a++ {
+        for b := 0; b < 5; b++ {
+            ra, rb := -1.0, -1.0
+            if a < 3 {
+                ra = 1
+            }
+            if b < 3 {
+                rb = 1
+            }
+            independent = append(independent, []float64{ra, rb})
+        }
+    }
+
+    if got := jointKelly(single); math.Abs(got[0]-0.2) > 1e-4 {
+        t.Errorf("single bet = %.4f, want full Kelly 0.2", got[0])
+    }
+    if got := jointKelly(correlated); math.Abs(got[0]+got[1]-0.2) > 1e-4 {
+        t.Errorf("identical bets = %.4f + %.4f, want 0.2 between them", got[0], got[1])
+    }
+    got := jointKelly(independent)
+    if math.Abs(got[0]-got[1]) > 1e-4 || got[0] >= 0.2 || got[0] < 0.15 {
+        t.Errorf("independent bets = %.4f, %.4f, want equal and a little under 0.2", got[0], got[1])
+    }
+}
+
+func TestSimultaneousKellySharesCorrelatedStakes(t *testing.T) {
+    withConfig(t, func(c *Config) {
+        c.ExposureRule = "simultaneous"
+        c.MaxStake, c.MaxGameExposure, c.MaxTeamExposure, c.MaxDailyExposure = 1, 1, 1, 1
+    })
+    game := Game{ID: "g1", HomeTeam: "Boston Celtics", AwayTeam: "Miami Heat"}
+    pricer := newParlayPricer([]Game{game}, nil, nil, nil)
+    sim := simulateGame(game, simParams{HomePoints: 116, AwayPoints: 108, MarginSD: 12, TotalSD: 18}, LiveGameState{}, false, 5000)
+    pricer.sims[game.ID] = sim
+
+    bets := []ValueBet{
+        {GameID: "g1", Market: "h2h", Team: "Boston Celtics", Odds: -150, CommenceTime: exposureDay},
+        {GameID: "g1", Market: "spreads", Team: "Boston Celtics", Point: -1.5, Odds: -130, CommenceTime: exposureDay},
+    }
+    for i := range bets {
+        prob, ok := sim.outcomeProbability(game, bets[i].Market, betOutcome(bets[i]), 0, 0)
+        if !ok {
+            t.Fatalf("%s not priced", bets[i].Market)
+        }
+        bets[i].HistoricalProb = prob
+    }
+    sizeStakes(bets)
+    separate := bets[0].Stake + bets[1].Stake
+    if bets[0].Stake == 0 || bets[1].Stake == 0 {
+        t.Fatalf("stakes %.2f, %.2f, want both bets to have an edge", bets[0].Stake, bets[1].Stake)
+    }
+
+    applyExposure(bets, nil, pricer)
+    together := bets[0].Stake + bets[1].Stake
+    if together >= separate*0.8 {
+        t.Errorf("stakes together = %.2f, want well under the %.2f they'd get separately", together, separate)
+    }
+    for i, bet := range bets {
+        if bet.Stake < 0 || bet.Stake > 0 && !strings.Contains(bet.StakeNote, "Kelly together") {
+            t.Errorf("bet %d stake %.2f note %q", i, bet.Stake, bet.StakeNote)
+        }
+    }
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+    "context"
     "encoding/json"
     "fmt"
     "io/ioutil"
     "math"
-    "net/http"
+    "net/url"
+    "os"
     "os/exec"
     "sort"
+    "strconv"
     "strings"
     "time"
 )
@@ -22,22 +25,22 @@ func loadAPIKey() (string, error) {
     return strings.TrimSpace(string(data)), nil
 }
 
-func initClient() (string, error) {
-    apiKey, err := loadAPIKey()
+func initClient() (*oddsClient, error) {
+    keys, err := resolveAPIKeys()
     if err != nil {
-        return "", fmt.Errorf("failed to load API key: %v", err)
+        return nil, fmt.Errorf("failed to load API key: %v", err)
     }
     
-    if apiKey == "" {
-        return "", fmt.Errorf("API key is empty - please check api.txt")
-    }
-    
-    return apiKey, nil
+    return newOddsClient(keys), nil
 }
 
+
 type Outcome struct {
 	Name  string  `json:"name"`
 	Price float64 `json:"price"`
+	// Line for spreads and totals; team totals name the team in Description
+	Point       float64 `json:"point,omitempty"`
+	Description string  `json:"description,omitempty"`
 }
 
 type Market struct {
@@ -54,31 +57,72 @@ type Bookmaker struct {
 }
 
 type Game struct {
-	ID         string      `json:"id"`
-	SportKey   string      `json:"sport_key"`
-	SportTitle string      `json:"sport_title"`
-	HomeTeam   string      `json:"home_team"`
-	AwayTeam   string      `json:"away_team"`
-	Bookmakers []Bookmaker `json:"bookmakers"`
+	ID           string      `json:"id"`
+	SportKey     string      `json:"sport_key"`
+	SportTitle   string      `json:"sport_title"`
+	CommenceTime time.Time   `json:"commence_time"`
+	HomeTeam     string      `json:"home_team"`
+	AwayTeam     string      `json:"away_team"`
+	Bookmakers   []Bookmaker `json:"bookmakers"`
+	FetchedAt    time.Time   `json:"fetched_at"`
 }
 
+
+
+
 type ValueBet struct {
-	Game           string
-	Team           string
-	Odds           float64
-	ImpliedProb    float64
-	HistoricalProb float64
-	Value          float64
-	NetRating      float64
-	Confidence     float64
+	Game           string          `json:"game"`
+	GameID         string          `json:"game_id"`
+	CommenceTime   time.Time       `json:"commence_time"`
+	Phase          string          `json:"phase"`
+	Bookmaker      string          `json:"bookmaker"`
+	Market         string          `json:"market"`
+	Team           string          `json:"team"`
+	Point          float64         `json:"point,omitempty"`
+	Odds           float64         `json:"odds"`
+	ImpliedProb    float64         `json:"implied_prob"`
+	HistoricalProb float64         `json:"historical_prob"`
+	Value          float64         `json:"value"`
+	NetRating      float64         `json:"net_rating"`
+	Confidence     float64         `json:"confidence"`
+	LastUpdate     time.Time       `json:"last_update"`
+	Stale          bool            `json:"stale"`
+	StaleReason    string          `json:"stale_reason,omitempty"`
+	Projection     float64         `json:"projection,omitempty"`
+	Kelly          float64         `json:"kelly"`
+	Stake          float64         `json:"stake"`
+	StakeNote      string          `json:"stake_note,omitempty"`
+	Adjustments    []Adjustment    `json:"adjustments,omitempty"`
+	Breakdown      []BreakdownStep `json:"breakdown,omitempty"`
+}
+
+// Adjustment is one factor's shift to a team's win probability
+type Adjustment struct {
+	Factor string  `json:"factor"`
+	Value  float64 `json:"value"`
 }
 
 
 type TeamStats struct {
-	WinRate         float64 `json:"win_rate"`
-	AvgPointsFor    float64 `json:"avg_points_for"`
-	AvgPointsAgainst float64 `json:"avg_points_against"`
-	LastTenGames    []bool  `json:"last_ten_games"` 
+	WinRate          float64      `json:"win_rate"`
+	AvgPointsFor     float64      `json:"avg_points_for"`
+	AvgPointsAgainst float64      `json:"avg_points_against"`
+	LastTenGames     []bool       `json:"last_ten_games"`
+	RecentGames      []RecentGame `json:"recent_games"`
+	Home             VenueSplit   `json:"home"`
+	Away             VenueSplit   `json:"away"`
+	InjuryImpact     float64      `json:"injury_impact,omitempty"`
+	Unavailable      []string     `json:"unavailable,omitempty"`
+	Rating           *TeamRating  `json:"rating,omitempty"`
+}
+
+// RecentGame is one of a team's latest results, most recent first
+type RecentGame struct {
+	GameDate string  `json:"game_date"`
+	Opponent string  `json:"opponent"`
+	Home     bool    `json:"home"`
+	Margin   float64 `json:"margin"`
+	Won      bool    `json:"won"`
 }
 
 type NBATeam struct {
@@ -200,71 +244,128 @@ func assessComebackProbability(scoreDiff int, timeRemaining float64) bool {
     return true
 }
 
-func calculateValue(game Game, stats map[string]TeamStats, liveScores map[string]LiveGameState) []ValueBet {
+// calculateValue prices the game at every configured bookmaker
+func calculateValue(game Game, stats map[string]TeamStats, liveScores map[string]LiveGameState, schedule teamSchedule, logs *playerLogs) []ValueBet {
+    var valueBets []ValueBet
+    for _, bookKey := range config.Bookmakers {
+        valueBets = append(valueBets, calculateBookValue(game, bookKey, stats, liveScores, schedule, logs)...)
+    }
+    return valueBets
+}
+
+// calculateBookValue prices the h2h market of a single bookmaker
+func calculateBookValue(game Game, bookKey string, teamStats map[string]TeamStats, liveScores map[string]LiveGameState, schedule teamSchedule, logs *playerLogs) []ValueBet {
     var valueBets []ValueBet
     
     // Check if game is live
     gameKey := fmt.Sprintf("%s vs %s", game.AwayTeam, game.HomeTeam)
     liveGame, isLive := liveScores[gameKey]
     
+    phase := gamePhase(game, liveGame, isLive, game.asOf())
+
     // Skip finished games
-    if isLive && liveGame.Status == 3 {
+    if phase == phaseFinal {
+        return valueBets
+    }
+
+    if config.LiveOnly && phase != phaseInPlay {
+        return valueBets
+    }
+
+    // A game that has tipped off can't be priced without its score
+    inPlay := phase == phaseInPlay
+    if inPlay && !(isLive && liveGame.Status == 2) {
         return valueBets
     }
 
     for _, bookmaker := range game.Bookmakers {
-        if bookmaker.Key != "betmgm" {
+        if bookmaker.Key != bookKey {
             continue
         }
 
+        // Markets other than the moneyline are priced from one simulation
+        var sim *gameSim
         for _, market := range bookmaker.Markets {
-            if market.Key != "h2h" {
+            staleReason := marketStaleness(game, bookmaker, market)
+
+            // Player props are projected from game logs, before tip-off only
+            if _, isProp := propStats[market.Key]; isProp {
+                if inPlay {
+                    continue
+                }
+                for _, valueBet := range calculatePropValue(game, bookmaker, market, logs) {
+                    if applyStaleness(&valueBet, staleReason) {
+                        valueBets = append(valueBets, valueBet)
+                    }
+                }
                 continue
             }
 
-            for _, outcome := range market.Outcomes {
-                if stats, exists := stats[outcome.Name]; exists {
+            if market.Key != "h2h" {
+                if sim == nil {
+                    if sim = newGameSim(game, teamStats, schedule, liveGame, inPlay); sim == nil {
+                        continue
+                    }
+                }
+                for _, outcome := range market.Outcomes {
+                    simProb, ok := sim.outcomeProbability(game, market.Key, outcome, 0, 0)
+                    if !ok {
+                        continue
+                    }
                     impliedProb := americanToImpliedProb(outcome.Price)
-                    recentForm := calculateRecentForm(stats.LastTenGames)
-                    
-                    // Base historical probability
-                    historicalProb := (stats.WinRate*0.7 + recentForm*0.3)
-                    
-                    // Adjust for home court advantage (3-4% historically)
-                    if outcome.Name == game.HomeTeam {
-                        historicalProb += 0.035
-                    } else {
-                        historicalProb -= 0.035
+                    value := simProb - impliedProb
+                    if value <= config.MinEdge {
+                        continue
                     }
 
-                    // If game is live, adjust probabilities based on score
-                    if isLive && liveGame.Status == 2 {
-                        scoreDiff := liveGame.HomeScore - liveGame.AwayScore
-                        if outcome.Name == game.AwayTeam {
-                            scoreDiff = -scoreDiff
-                        }
-                        
-                        timeRemaining := calculateTimeRemaining(liveGame.Period, liveGame.Clock)
-                        
-                        // Calculate win probability adjustment based on score and time
-                        probAdjustment := calculateLiveWinProbability(scoreDiff, timeRemaining)
-                        
-                        // Blend original probability with live game state
-                        historicalProb = (historicalProb * 0.2) + (probAdjustment * 0.8)
+                    valueBet := ValueBet{
+                        Game:           gameKey,
+                        GameID:         game.ID,
+                        CommenceTime:   game.CommenceTime,
+                        Phase:          phase,
+                        Bookmaker:      bookmaker.Key,
+                        Market:         market.Key,
+                        Team:           selectionName(outcome),
+                        Point:          outcome.Point,
+                        Odds:           outcome.Price,
+                        ImpliedProb:    impliedProb,
+                        HistoricalProb: simProb,
+                        Value:          value,
+                        Confidence:     simulatedConfidence(game, sim, market.Key, outcome, teamStats, schedule, impliedProb),
+                        LastUpdate:     market.LastUpdate,
+                    }
+                    if stats, exists := teamStats[outcome.Name]; exists {
+                        valueBet.NetRating = adjustedNetRating(stats)
                     }
+                    if applyStaleness(&valueBet, staleReason) {
+                        valueBets = append(valueBets, valueBet)
+                    }
+                }
+                continue
+            }
+
+            for _, outcome := range market.Outcomes {
+                if stats, exists := teamStats[outcome.Name]; exists {
+                    impliedProb := americanToImpliedProb(outcome.Price)
+                    historicalProb, adjustments, breakdown := winProbability(game, outcome.Name, teamStats, schedule, liveGame, inPlay)
                     
-                    // Net rating (points scored vs points allowed)
-                    netRating := stats.AvgPointsFor - stats.AvgPointsAgainst
+                    // Net rating per 100 possessions, or points scored vs points allowed if unrated
+                    netRating := adjustedNetRating(stats)
                     
                     // Calculate value (difference between actual probability and implied probability)
                     value := historicalProb - impliedProb
-                    
-                    // Calculate confidence score
-                    confidence := calculateConfidence(value, netRating, recentForm)
 
-                    if value > 0 {
+                    if value > config.MinEdge {
+                        // Chance the edge survives the model's sampling error
+                        confidence := calculateConfidence(game, outcome.Name, teamStats, schedule, liveGame, inPlay, impliedProb)
+
                         valueBet := ValueBet{
                             Game:           gameKey,
+                            GameID:         game.ID,
+                            CommenceTime:   game.CommenceTime,
+                            Phase:          phase,
+                            Bookmaker:      bookmaker.Key,
+                            Market:         market.Key,
                             Team:           outcome.Name,
                             Odds:           outcome.Price,
                             ImpliedProb:    impliedProb,
@@ -272,10 +373,16 @@ func calculateValue(game Game, stats map[string]TeamStats, liveScores map[string
                             Value:          value,
                             NetRating:      netRating,
                             Confidence:     confidence,
+                            LastUpdate:     market.LastUpdate,
+                            Adjustments:    adjustments,
+                            Breakdown:      breakdown,
+                        }
+                        if !applyStaleness(&valueBet, staleReason) {
+                            continue
                         }
                         
                         // If game is live, check if bet is still viable
-                        if isLive && liveGame.Status == 2 {
+                        if inPlay {
                             if isViableLiveBet(valueBet, liveGame, outcome.Name == game.HomeTeam) {
                                 valueBets = append(valueBets, valueBet)
                             }
@@ -291,6 +398,66 @@ func calculateValue(game Game, stats map[string]TeamStats, liveScores map[string
     return valueBets
 }
 
+// winProbability is the model's probability that team wins game, with the
+// adjustments applied and the breakdown of how it was reached
+func winProbability(game Game, team string, teamStats map[string]TeamStats, schedule teamSchedule, liveGame LiveGameState, inPlay bool) (float64, []Adjustment, []BreakdownStep) {
+    stats := teamStats[team]
+    recentForm := calculateRecentForm(stats, teamStats)
+    
+    // Base historical probability
+    historicalProb := (stats.WinRate*config.SeasonWeight + recentForm*config.RecentWeight)
+    breakdown := addStep(nil, "season_win_rate", stats.WinRate*config.SeasonWeight)
+    breakdown = addStep(breakdown, "recent_form", recentForm*config.RecentWeight)
+
+    // Blend in opponent-adjusted ratings when both teams are rated
+    opponent := game.HomeTeam
+    if team == game.HomeTeam {
+        opponent = game.AwayTeam
+    }
+    if ratingProb, rated := ratingWinProbability(stats, teamStats[opponent]); rated {
+        blended := ratingProb*config.RatingWeight + historicalProb*(1-config.RatingWeight)
+        breakdown = addStep(breakdown, "team_ratings", blended-historicalProb)
+        historicalProb = blended
+    }
+    
+    // Adjust for home court advantage from both teams' venue splits
+    homeEdge := homeCourtEdge(teamStats, game)
+    if team != game.HomeTeam {
+        homeEdge = -homeEdge
+    }
+    historicalProb += homeEdge
+    breakdown = addStep(breakdown, "home_court", homeEdge)
+
+    // Rest and travel relative to the opponent
+    adjustments := scheduleAdjustments(schedule, game, team)
+    // Players missing on either side
+    adjustments = append(adjustments, injuryAdjustments(teamStats, game, team)...)
+    historicalProb += sumAdjustments(adjustments)
+    for _, adjustment := range adjustments {
+        breakdown = addStep(breakdown, adjustment.Factor, adjustment.Value)
+    }
+
+    // If game is live, adjust probabilities based on score
+    if inPlay {
+        scoreDiff := liveGame.HomeScore - liveGame.AwayScore
+        if team == game.AwayTeam {
+            scoreDiff = -scoreDiff
+        }
+        
+        timeRemaining := calculateTimeRemaining(liveGame.Period, liveGame.Clock)
+        
+        // Calculate win probability adjustment based on score and time
+        probAdjustment := calculateLiveWinProbability(scoreDiff, timeRemaining)
+        
+        // Blend original probability with live game state
+        blended := (historicalProb * (1 - config.LiveWeight)) + (probAdjustment * config.LiveWeight)
+        breakdown = addStep(breakdown, "live_state", blended-historicalProb)
+        historicalProb = blended
+    }
+
+    return historicalProb, adjustments, breakdown
+}
+
 // Add missing helper functions
 func americanToImpliedProb(americanOdds float64) float64 {
 	if americanOdds > 0 {
@@ -308,89 +475,97 @@ type Sport struct {
 	HasOdds  bool   `json:"has_odds"`
 }
 
-func fetchNBAStats() (map[string]TeamStats, error) {
-    cmd := exec.Command("python", "nba_stats_fetcher.py")
-    output, err := cmd.Output()
-    if err != nil {
-        return nil, fmt.Errorf("error running Python script: %v", err)
-    }
-
-    var rawStats map[string]struct {
-        WinRate         float64 `json:"win_rate"`
-        AvgPointsFor    float64 `json:"avg_points_for"`
-        AvgPointsAgainst float64 `json:"avg_points_against"`
-        LastTenGames    []bool  `json:"last_ten_games"`
-    }
-
-    if err := json.Unmarshal(output, &rawStats); err != nil {
-        return nil, fmt.Errorf("error parsing Python output: %v", err)
-    }
-
-    // Convert to our TeamStats format
-    teamStats := make(map[string]TeamStats)
-    for team, stats := range rawStats {
-        teamStats[team] = TeamStats{
-            WinRate:         stats.WinRate,
-            AvgPointsFor:    stats.AvgPointsFor,
-            AvgPointsAgainst: stats.AvgPointsAgainst,
-            LastTenGames:    stats.LastTenGames,
-        }
-    }
-
-    return teamStats, nil
+func fetchSports(ctx context.Context, client *oddsClient) ([]Sport, error) {
+	var sports []Sport
+	err := client.getJSON(ctx, "sports", "", nil, &sports)
+	return sports, err
 }
 
-func fetchSports(apiKey string) ([]Sport, error) {
-    url := fmt.Sprintf("%s?apiKey=%s", baseURL, apiKey)
-    
-	resp, err := http.Get(url)
+func fetchOdds(ctx context.Context, client *oddsClient, sportKey string) ([]Game, error) {
+	query := url.Values{
+		"regions":    {config.Regions},
+		"markets":    {strings.Join(config.Markets, ",")},
+		"oddsFormat": {"american"},
+	}
+
+	start, end, windowed, err := gameWindow(time.Now())
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	if windowed {
+		query.Set("commenceTimeFrom", start.UTC().Format(time.RFC3339))
+		query.Set("commenceTimeTo", end.UTC().Format(time.RFC3339))
+	}
 
-	var sports []Sport
-	body, err := ioutil.ReadAll(resp.Body)
+	var games []Game
+	err = client.getJSON(ctx, "odds", "/"+sportKey+"/odds", query, &games)
+	fetchedAt := time.Now()
+	for i := range games {
+		games[i].FetchedAt = fetchedAt
+	}
 	if err != nil {
-		return nil, err
+		return games, err
 	}
+	addEventOdds(ctx, client, sportKey, games)
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].CommenceTime.Before(games[j].CommenceTime)
+	})
+	return filterGamesByWindow(games, fetchedAt)
+}
 
-	err = json.Unmarshal(body, &sports)
-	return sports, err
+// GameScore is an entry from the odds API scores endpoint
+type GameScore struct {
+	ID        string `json:"id"`
+	Completed bool   `json:"completed"`
+	HomeTeam  string `json:"home_team"`
+	AwayTeam  string `json:"away_team"`
+	Scores    []struct {
+		Name  string `json:"name"`
+		Score string `json:"score"`
+	} `json:"scores"`
 }
 
-func fetchOdds(sportKey string, apiKey string) ([]Game, error) {
-	url := fmt.Sprintf("%s/%s/odds?apiKey=%s&regions=us&markets=h2h,spreads&oddsFormat=american", 
-		baseURL, sportKey, apiKey)
-	
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+// pointsFor returns the team's score and its opponent's
+func (g GameScore) pointsFor(team string) (int, int, bool) {
+	points := make(map[string]int)
+	for _, s := range g.Scores {
+		var score int
+		if _, err := fmt.Sscanf(s.Score, "%d", &score); err != nil {
+			return 0, 0, false
+		}
+		points[s.Name] = score
 	}
-	defer resp.Body.Close()
 
-	var games []Game
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	opponent := g.HomeTeam
+	if team == g.HomeTeam {
+		opponent = g.AwayTeam
 	}
+	teamScore, ok1 := points[team]
+	opponentScore, ok2 := points[opponent]
+	return teamScore, opponentScore, ok1 && ok2
+}
 
-	err = json.Unmarshal(body, &games)
-	return games, err
+func fetchScores(ctx context.Context, client *oddsClient, sportKey string, daysFrom int) ([]GameScore, error) {
+	query := url.Values{"daysFrom": {strconv.Itoa(daysFrom)}}
+
+	var scores []GameScore
+	err := client.getJSON(ctx, "scores", "/"+sportKey+"/scores", query, &scores)
+	return scores, err
 }
 
-func displayBetMGMOdds(games []Game) {
+func displayBookmakerOdds(games []Game) {
 	for _, game := range games {
 		fmt.Printf("\n%s vs %s\n", game.HomeTeam, game.AwayTeam)
+		fmt.Printf("Tip-off: %s\n", formatTipoff(game.CommenceTime, game.asOf()))
 		fmt.Printf("----------------------------------------\n")
 
 		for _, bookmaker := range game.Bookmakers {
-			if bookmaker.Key == "betmgm" {
-				fmt.Printf("BetMGM Odds:\n")
+			if isConfiguredBookmaker(bookmaker.Key) {
+				fmt.Printf("%s Odds:\n", bookmaker.Title)
 				for _, market := range bookmaker.Markets {
-					fmt.Printf("\nMarket: %s\n", market.Key)
+					fmt.Printf("\nMarket: %s\n", marketLabel(market.Key))
 					for _, outcome := range market.Outcomes {
-						fmt.Printf("  %s: %+v\n", outcome.Name, outcome.Price)
+						fmt.Printf("  %s: %+v\n", formatSelection(market.Key, selectionName(outcome), outcome.Point), outcome.Price)
 					}
 				}
 			}
@@ -398,54 +573,13 @@ func displayBetMGMOdds(games []Game) {
 	}
 }
 
-// Update fetchTeamStats to use TeamName instead of Name
-func fetchTeamStats() (map[string]TeamStats, error) {
-    cmd := exec.Command("python", "nba_stats_fetcher.py")
-    output, err := cmd.Output()
-    if err != nil {
-        return nil, fmt.Errorf("error running Python script: %v", err)
-    }
-
-    var rawStats map[string]struct {
-        WinRate         float64 `json:"win_rate"`
-        AvgPointsFor    float64 `json:"avg_points_for"`
-        AvgPointsAgainst float64 `json:"avg_points_against"`
-        LastTenGames    []bool  `json:"last_ten_games"`
-    }
-
-    if err := json.Unmarshal(output, &rawStats); err != nil {
-        return nil, fmt.Errorf("error parsing Python output: %v", err)
-    }
-
-    // Convert to our TeamStats format
-    teamStats := make(map[string]TeamStats)
-    for team, stats := range rawStats {
-        teamStats[team] = TeamStats{
-            WinRate:         stats.WinRate,
-            AvgPointsFor:    stats.AvgPointsFor,
-            AvgPointsAgainst: stats.AvgPointsAgainst,
-            LastTenGames:    stats.LastTenGames,
-        }
-    }
-
-    return teamStats, nil
-}
-
-// Helper function to estimate last 10 games from win streak
-func calculateLastTenFromStreak(streak, lastN int) []bool {
-	games := make([]bool, 10)
-	if streak > 0 {
-		// Team is on a winning streak
-		for i := 0; i < min(streak, 10); i++ {
-			games[i] = true
-		}
-	} else {
-		// Team is on a losing streak
-		for i := 0; i < min(-streak, 10); i++ {
-			games[i] = false
+func isConfiguredBookmaker(key string) bool {
+	for _, bookKey := range config.Bookmakers {
+		if bookKey == key {
+			return true
 		}
 	}
-	return games
+	return false
 }
 
 func min(a, b int) int {
@@ -455,146 +589,183 @@ func min(a, b int) int {
 	return b
 }
 
-func calculateRecentForm(games []bool) float64 {
-	if len(games) == 0 {
+// calculateRecentForm rates a team's recent games on the win probability
+// scale. Each margin is credited with the opponent's net rating and the venue,
+// and later games count for more.
+func calculateRecentForm(team TeamStats, stats map[string]TeamStats) float64 {
+	if len(team.RecentGames) == 0 {
 		return 0.5 // Default to 50% if no games data
 	}
-	
-	wins := 0
-	for _, win := range games {
-		if win {
-			wins++
+
+	homeEdge := leagueHomeEdge(stats) / config.PointValue
+	weight, weightSum, total := 1.0, 0.0, 0.0
+	for _, game := range team.RecentGames {
+		margin := math.Max(-maxFormMargin, math.Min(game.Margin, maxFormMargin))
+		if opponent, ok := stats[game.Opponent]; ok {
+			// Today's injuries didn't play in past games
+			margin += opponent.AvgPointsFor + opponent.InjuryImpact - opponent.AvgPointsAgainst
+		}
+		if game.Home {
+			margin -= homeEdge
+		} else {
+			margin += homeEdge
 		}
+		total += weight * margin
+		weightSum += weight
+		weight *= config.FormDecay
 	}
-	return float64(wins) / float64(len(games))
+
+	form := 0.5 + total/weightSum*config.PointValue
+	return math.Max(0.05, math.Min(form, 0.95))
 }
 
-func calculateConfidence(value, netRating, recentForm float64) float64 {
-    valueWeight := 0.5
-    netRatingWeight := 0.3
-    recentFormWeight := 0.2
+// Blowouts say little more than comfortable wins
+const maxFormMargin = 20.0
 
-    // Normalize value (typical range -0.3 to 0.3)
-    normalizedValue := math.Min(math.Max(value*3, 0), 1.0)
-    
-    // Normalize net rating (typical range -10 to +10)
-    normalizedNetRating := (netRating + 10) / 20
-    
-    // Combine factors with exponential scaling to make high confidence harder
-    confidence := (normalizedValue * valueWeight) +
-        (normalizedNetRating * netRatingWeight) +
-        (recentForm * recentFormWeight)
-    
-    // Apply exponential scaling to make high confidence harder to achieve
-    return math.Pow(math.Max(0, math.Min(1, confidence)), 1.5)
+// CombinedData is the payload printed by nba_stats_fetcher.py
+type CombinedData struct {
+    Stats      map[string]TeamStats     `json:"stats"`
+    LiveScores map[string]LiveGameState `json:"live_scores"`
+    Schedule   []ScheduleGame           `json:"schedule"`
+    Players    []PlayerStats            `json:"players"`
+    GameLogs   []PlayerGameLog          `json:"game_logs"`
 }
 
-func main() {
-    fmt.Println("Starting NBA betting analysis...")
-    
-    // Load API key first
-    apiKey, err := loadAPIKey()
-    if err != nil {
-        fmt.Printf("Error loading API key: %v\n", err)
-        return
+// Season stats change slowly and live scores quickly, so each part is
+// fetched and cached on its own
+func fetchStats(ctx context.Context) (map[string]TeamStats, error) {
+    var combinedData CombinedData
+    if err := runStatsFetcher(ctx, "stats", &combinedData); err != nil {
+        return nil, err
+    }
+    if len(combinedData.Stats) == 0 {
+        return nil, fmt.Errorf("Python script returned no team stats")
     }
+    return checkTeamStats(combinedData.Stats)
+}
 
-    // Step 1: Fetch NBA stats and live scores from Python script
-    fmt.Println("\nFetching NBA data and live scores...")
-    var combinedData struct {
-        Stats      map[string]TeamStats     `json:"stats"`
-        LiveScores map[string]LiveGameState `json:"live_scores"`
+func fetchLiveScores(ctx context.Context) (map[string]LiveGameState, error) {
+    var combinedData CombinedData
+    if err := runStatsFetcher(ctx, "live", &combinedData); err != nil {
+        return nil, err
     }
+    return combinedData.LiveScores, nil
+}
 
-    cmd := exec.Command("python", "nba_stats_fetcher.py")
-    output, err := cmd.CombinedOutput()
-    if err != nil {
-        fmt.Printf("Error running Python script: %v\n", err)
-        fmt.Printf("Python output: %s\n", string(output))
-        return
+// runStatsFetcher runs one mode of nba_stats_fetcher.py, decoding its output
+// into combinedData. Non-empty results are cached under the mode's TTL.
+func runStatsFetcher(ctx context.Context, mode string, combinedData *CombinedData) error {
+    key := cacheKey(mode, "nba_stats_fetcher.py")
+    output, cached := cache.lookup(mode, key)
+    if !cached {
+        cmd := exec.CommandContext(ctx, "python", "nba_stats_fetcher.py", mode)
+        var err error
+        output, err = cmd.Output()
+        if err != nil {
+            stderr := ""
+            if exitErr, ok := err.(*exec.ExitError); ok {
+                stderr = string(exitErr.Stderr)
+            }
+            return fmt.Errorf("error running Python script: %v: %s", err, strings.TrimSpace(stderr))
+        }
     }
 
-    if err := json.Unmarshal(output, &combinedData); err != nil {
-        fmt.Printf("Error parsing Python output: %v\n", err)
-        fmt.Printf("Raw output: %s\n", string(output))
-        return
+    if err := json.Unmarshal(output, combinedData); err != nil {
+        return fmt.Errorf("error parsing Python output: %v\nRaw output: %s", err, string(output))
     }
 
-    fmt.Printf("Successfully loaded data for %d teams and %d live games\n", 
-        len(combinedData.Stats), len(combinedData.LiveScores))
+    // The fetcher reports upstream failures as empty results; don't pin those
+    empty := (mode == "stats" && len(combinedData.Stats) == 0) ||
+        (mode == "schedule" && len(combinedData.Schedule) == 0) ||
+        (mode == "players" && len(combinedData.Players) == 0) ||
+        (mode == "gamelogs" && len(combinedData.GameLogs) == 0)
+    if !cached && !empty {
+        cache.store(key, output)
+    }
+    return nil
+}
 
-    // Step 2: Fetch available sports - passing apiKey
-    fmt.Println("\nFetching available sports...")
-    sports, err := fetchSports(apiKey)  // Fixed: passing apiKey
-    if err != nil {
-        fmt.Printf("Error fetching sports: %v\n", err)
+// progressf reports pipeline progress, keeping stdout clean for JSON output
+func progressf(format string, args ...interface{}) {
+    if config.Output == "json" {
+        fmt.Fprintf(os.Stderr, format, args...)
         return
     }
+    fmt.Printf(format, args...)
+}
 
-    fmt.Println("Available Sports:")
-    for _, sport := range sports {
-        fmt.Printf("- %s (%s)\n", sport.Title, sport.Key)
-    }
+func main() {
+    os.Exit(runCLI(os.Args[1:]))
+}
 
-    // Step 3: Fetch NBA odds - passing apiKey
-    fmt.Println("\nFetching NBA odds...")
-    games, err := fetchOdds("basketball_nba", apiKey)  // Fixed: passing apiKey
-    if err != nil {
-        fmt.Printf("Error fetching odds: %v\n", err)
-        return
+// analyzeValueBets prints the top value bets and returns them
+func analyzeValueBets(games []Game, teamStats map[string]TeamStats, liveScores map[string]LiveGameState, schedule teamSchedule, logs *playerLogs) []ValueBet {
+    valueBets := findValueBets(games, teamStats, liveScores, schedule, logs)
+    if len(valueBets) > config.TopN {
+        valueBets = valueBets[:config.TopN]
     }
-    fmt.Printf("Successfully fetched odds for %d games\n", len(games))
-
-    // Step 4: Display current odds
-    fmt.Println("\nCurrent BetMGM Odds:")
-    displayBetMGMOdds(games)
-
-    // Step 5: Analyze betting opportunities
-    fmt.Println("\nAnalyzing value betting opportunities...")
-    analyzeValueBets(games, combinedData.Stats, combinedData.LiveScores)
-
-    // Optional: Display any live game information
-    if len(combinedData.LiveScores) > 0 {
-        fmt.Println("\nCurrent Live Games:")
-        for gameKey, liveGame := range combinedData.LiveScores {
-            fmt.Printf("\n%s:\n", gameKey)
-            fmt.Printf("Period: %d, Clock: %s\n", liveGame.Period, liveGame.Clock)
-            fmt.Printf("Score: %s %d - %d %s\n",
-                liveGame.HomeTeam, liveGame.HomeScore,
-                liveGame.AwayScore, liveGame.AwayTeam)
+    // Stakes are sized bet by bet; cap what rides on any one game, team or day
+    applyExposure(valueBets, openLedgerEntries(), newParlayPricer(games, teamStats, liveScores, schedule))
+
+    if config.Output == "json" {
+        if err := printJSON(valueBets); err != nil {
+            fmt.Fprintf(os.Stderr, "%v\n", err)
         }
+        return valueBets
     }
 
-    fmt.Println("\nAnalysis complete!")
-}
-
-func analyzeValueBets(games []Game, teamStats map[string]TeamStats, liveScores map[string]LiveGameState) {
     fmt.Printf("\nValue Betting Analysis:\n")
     fmt.Printf("=============================\n")
 
+    // Display top value bets
+    for i, bet := range valueBets {
+        displayValueBet(i+1, bet, liveScores)
+    }
+    return valueBets
+}
+
+// findValueBets prices every game at the configured bookmakers and returns the bets sorted by confidence
+func findValueBets(games []Game, teamStats map[string]TeamStats, liveScores map[string]LiveGameState, schedule teamSchedule, logs *playerLogs) []ValueBet {
     var valueBets []ValueBet
     for _, game := range games {
-        bets := calculateValue(game, teamStats, liveScores)
+        bets := calculateValue(game, teamStats, liveScores, schedule, logs)
         valueBets = append(valueBets, bets...)
     }
+    sizeStakes(valueBets)
 
-    // Sort by confidence (highest first)
+    sortByConfidence(valueBets)
+    return valueBets
+}
+
+// probabilitySource names what priced a market: the win model for full-game
+// moneylines, the game simulation for other team markets and the player's
+// projection for props
+func probabilitySource(marketKey string) string {
+    if _, isProp := propStats[marketKey]; isProp {
+        return "player projection"
+    }
+    if marketKey == "h2h" {
+        return "win model"
+    }
+    return "game simulation"
+}
+
+// Sort by tier, then confidence (highest first), so stale prices rank with
+// the tier they're capped at
+func sortByConfidence(valueBets []ValueBet) {
     sort.Slice(valueBets, func(i, j int) bool {
+        ri, rj := tierRank(betTier(valueBets[i])), tierRank(betTier(valueBets[j]))
+        if ri != rj {
+            return ri < rj
+        }
         return valueBets[i].Confidence > valueBets[j].Confidence
     })
-
-    // Display top value bets
-    for i, bet := range valueBets {
-        if i >= 5 {
-            break
-        }
-        displayValueBet(i+1, bet, liveScores)
-    }
 }
 
 func displayValueBet(index int, bet ValueBet, liveScores map[string]LiveGameState) {
     fmt.Printf("\nValue Bet #%d:\n", index)
     fmt.Printf("Game: %s\n", bet.Game)
+    fmt.Printf("Tip-off: %s [%s]\n", formatTipoff(bet.CommenceTime, time.Now()), bet.Phase)
     
     if liveGame, isLive := liveScores[bet.Game]; isLive {
         fmt.Printf("\nLIVE GAME STATUS:\n")
@@ -614,25 +785,52 @@ func displayValueBet(index int, bet ValueBet, liveScores map[string]LiveGameStat
     }
 
     fmt.Printf("\nBETTING ANALYSIS:\n")
-    fmt.Printf("Recommended Bet: %s\n", bet.Team)
+    fmt.Printf("Market: %s\n", marketLabel(bet.Market))
+    fmt.Printf("Recommended Bet: %s\n", betSelection(bet))
     fmt.Printf("Current Odds: %+.2f\n", bet.Odds)
+    if bet.Stale {
+        fmt.Printf("Odds Status: STALE - %s\n", bet.StaleReason)
+    }
     fmt.Printf("Implied Win Probability: %.1f%%\n", bet.ImpliedProb*100)
-    fmt.Printf("Historical Win Rate: %.1f%%\n", bet.HistoricalProb*100)
+    fmt.Printf("Model Probability: %.1f%% (%s)\n", bet.HistoricalProb*100, probabilitySource(bet.Market))
     fmt.Printf("Value Edge: %.1f%%\n", bet.Value*100)
-    fmt.Printf("Net Rating: %+.1f\n", bet.NetRating)
-    fmt.Printf("Confidence Score: %.3f\n", bet.Confidence)
+    if base, _ := splitMarketKey(bet.Market); base == "h2h" || base == "spreads" {
+        fmt.Printf("Net Rating: %+.1f\n", bet.NetRating)
+    }
+    if bet.Projection > 0 {
+        fmt.Printf("Projection: %.1f\n", bet.Projection)
+    }
+    fmt.Printf("Confidence (P(edge > 0)): %.1f%%\n", bet.Confidence*100)
+    fmt.Printf("Suggested Stake: %s\n", formatStake(bet))
+    if len(bet.Adjustments) > 0 {
+        fmt.Printf("Adjustments: %s\n", formatAdjustments(bet.Adjustments))
+    }
+    if config.Explain && len(bet.Breakdown) > 0 {
+        displayBreakdown(bet)
+    }
     
     fmt.Printf("\nRECOMMENDATION:\n")
-    if bet.Confidence > 0.6 {
-        fmt.Printf("Strong Value Bet - High confidence in favorable odds\n")
-    } else if bet.Confidence > 0.3 {
-        fmt.Printf("Moderate Value Bet - Decent odds but moderate risk\n")
-    } else {
-        fmt.Printf("Speculative Bet - Favorable odds but high risk\n")
+    switch tier := betTier(bet); {
+    case tier != confidenceTier(bet.Confidence):
+        label := tier + " Value Bet"
+        if tier == "Speculative" {
+            label = "Speculative Bet"
+        }
+        fmt.Printf("%s - %.0f%% chance the edge is real, downgraded because the price is stale\n", label, bet.Confidence*100)
+    case tier == "Strong":
+        fmt.Printf("Strong Value Bet - %.0f%% chance the edge is real\n", bet.Confidence*100)
+    case tier == "Moderate":
+        fmt.Printf("Moderate Value Bet - %.0f%% chance the edge is real, model error could erase it\n", bet.Confidence*100)
+    default:
+        fmt.Printf("Speculative Bet - only %.0f%% chance the edge is real\n", bet.Confidence*100)
     }
     
-    if bet.Value > 0.15 {
-        fmt.Printf("Large value gap detected (>15%%) - Worth strong consideration\n")
+    if bet.Stale {
+        fmt.Printf("Stale price - confirm the line at %s before betting, the edge may be gone\n", bet.Bookmaker)
+    }
+
+    if bet.Value > config.LargeEdge {
+        fmt.Printf("Large value gap detected (>%.0f%%) - Worth strong consideration\n", config.LargeEdge*100)
     }
     
     fmt.Printf("-------------------\n")
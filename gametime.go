@@ -0,0 +1,110 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+const (
+    phasePreGame = "pre-game"
+    phaseInPlay  = "in-play"
+    phaseFinal   = "final"
+)
+
+// A game that tipped off this long ago is over even without a scoreboard entry
+const maxGameLength = 3*time.Hour + 30*time.Minute
+
+// displayLocation is the time zone used for every printed time
+func displayLocation() *time.Location {
+    if config.Timezone == "" || config.Timezone == "Local" {
+        return time.Local
+    }
+    loc, err := time.LoadLocation(config.Timezone)
+    if err != nil {
+        return time.Local
+    }
+    return loc
+}
+
+func formatTipoff(commence, now time.Time) string {
+    if commence.IsZero() {
+        return "unknown"
+    }
+    local := commence.In(displayLocation()).Format("Mon Jan 2 3:04 PM MST")
+    if until := commence.Sub(now); until > 0 {
+        return fmt.Sprintf("%s (in %s)", local, strings.TrimSuffix(until.Round(time.Minute).String(), "0s"))
+    }
+    return local
+}
+
+// gamePhase classifies a game from the scoreboard when we have it, and from
+// its commence time otherwise
+func gamePhase(game Game, liveGame LiveGameState, isLive bool, now time.Time) string {
+    if isLive {
+        switch liveGame.Status {
+        case 2:
+            return phaseInPlay
+        case 3:
+            return phaseFinal
+        }
+        // Status 1 (scheduled) can lag tip-off, so fall through to the clock
+    }
+
+    if game.CommenceTime.IsZero() || now.Before(game.CommenceTime) {
+        return phasePreGame
+    }
+    if now.Sub(game.CommenceTime) > maxGameLength {
+        return phaseFinal
+    }
+    return phaseInPlay
+}
+
+// gameWindow returns the [start, end) range selected by window_start and
+// window_days, and whether any window is configured
+func gameWindow(now time.Time) (time.Time, time.Time, bool, error) {
+    if config.WindowStart == "" && config.WindowDays <= 0 {
+        return time.Time{}, time.Time{}, false, nil
+    }
+
+    loc := displayLocation()
+    today := now.In(loc)
+    start := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+    if config.WindowStart != "" && config.WindowStart != "today" {
+        parsed, err := time.ParseInLocation("2006-01-02", config.WindowStart, loc)
+        if err != nil {
+            return time.Time{}, time.Time{}, false, fmt.Errorf("invalid window_start %q, expected YYYY-MM-DD", config.WindowStart)
+        }
+        start = parsed
+    }
+
+    days := config.WindowDays
+    if days <= 0 {
+        days = 1
+    }
+    return start, start.AddDate(0, 0, days), true, nil
+}
+
+// filterGamesByWindow keeps games that tip off inside the configured window
+func filterGamesByWindow(games []Game, now time.Time) ([]Game, error) {
+    start, end, ok, err := gameWindow(now)
+    if err != nil || !ok {
+        return games, err
+    }
+
+    var filtered []Game
+    for _, game := range games {
+        if !game.CommenceTime.Before(start) && game.CommenceTime.Before(end) {
+            filtered = append(filtered, game)
+        }
+    }
+    return filtered, nil
+}
+
+// asOf is the moment the game's odds describe
+func (g Game) asOf() time.Time {
+    if g.FetchedAt.IsZero() {
+        return time.Now()
+    }
+    return g.FetchedAt
+}
@@ -0,0 +1,183 @@
+package main
+
+import "fmt"
+
+// hedgePlan is what an open position is worth now and what it takes to
+// hedge it at the best price on the other side
+type hedgePlan struct {
+    Entry     LedgerEntry `json:"entry"`
+    Phase     string      `json:"phase"`
+    ModelProb float64     `json:"model_prob"`
+    // FairValue is the position's expected return, stake included, at the
+    // model's current probability
+    FairValue float64 `json:"fair_value"`
+
+    HedgeBookmaker string  `json:"hedge_bookmaker,omitempty"`
+    HedgeSelection string  `json:"hedge_selection,omitempty"`
+    HedgeOdds      float64 `json:"hedge_odds,omitempty"`
+    // A lock stake returns the same profit whichever side wins; a
+    // break-even stake gets the stake back if the hedge wins and keeps the
+    // rest of the upside
+    LockStake       float64 `json:"lock_stake,omitempty"`
+    LockedProfit    float64 `json:"locked_profit,omitempty"`
+    BreakEvenStake  float64 `json:"break_even_stake,omitempty"`
+    BreakEvenProfit float64 `json:"break_even_profit,omitempty"`
+    // LockCost is the model's expected value given up by placing the lock
+    // stake, positive when hedging costs money
+    LockCost float64 `json:"lock_cost,omitempty"`
+
+    CashOut     float64 `json:"cash_out,omitempty"`
+    CashOutEdge float64 `json:"cash_out_edge,omitempty"`
+}
+
+// entryOutcome recovers the outcome a ledger entry backs
+func entryOutcome(entry LedgerEntry) Outcome {
+    return betOutcome(ValueBet{Market: entry.Market, Team: entry.Team, Point: entry.Point, Odds: entry.Odds})
+}
+
+// entryProbability is the model's current probability that entry wins:
+// the live-aware moneyline model for h2h, the simulation for other team
+// markets and the projection for player props
+func entryProbability(entry LedgerEntry, game Game, teamStats map[string]TeamStats, liveScores map[string]LiveGameState, schedule teamSchedule, logs *playerLogs) (float64, string, bool) {
+    liveGame, isLive := liveScores[fmt.Sprintf("%s vs %s", game.AwayTeam, game.HomeTeam)]
+    phase := gamePhase(game, liveGame, isLive, game.asOf())
+    inPlay := phase == phaseInPlay
+    if phase == phaseFinal || inPlay && !(isLive && liveGame.Status == 2) {
+        return 0, phase, false
+    }
+    outcome := entryOutcome(entry)
+
+    if stat, isProp := propStats[entry.Market]; isProp {
+        if inPlay || logs == nil {
+            return 0, phase, false
+        }
+        games, factor, ok := logs.propInputs(game, entry.Market, outcome.Description)
+        if !ok {
+            return 0, phase, false
+        }
+        return projectProp(games, stat, factor).sideProbability(outcome.Name, outcome.Point), phase, true
+    }
+    if entry.Market == "h2h" {
+        if _, ok := teamStats[entry.Team]; !ok {
+            return 0, phase, false
+        }
+        prob, _, _ := winProbability(game, entry.Team, teamStats, schedule, liveGame, inPlay)
+        return prob, phase, true
+    }
+    sim := newGameSim(game, teamStats, schedule, liveGame, inPlay)
+    if sim == nil {
+        return 0, phase, false
+    }
+    prob, ok := sim.outcomeProbability(game, entry.Market, outcome, 0, 0)
+    return prob, phase, ok
+}
+
+// oppositeOutcome reports whether other is the other side of outcome in
+// the same market: the other team, or the other side of the same line
+func oppositeOutcome(marketKey string, outcome, other Outcome) bool {
+    switch base, _ := splitMarketKey(marketKey); base {
+    case "h2h":
+        return other.Name != outcome.Name
+    case "spreads":
+        return other.Name != outcome.Name && other.Point == -outcome.Point
+    }
+    return other.Description == outcome.Description && other.Name != outcome.Name && other.Point == outcome.Point
+}
+
+// bestHedge finds the best price on the other side of entry across every
+// bookmaker quoting game
+func bestHedge(entry LedgerEntry, game Game) (Bookmaker, Outcome, bool) {
+    outcome := entryOutcome(entry)
+    var best Outcome
+    var bestBook Bookmaker
+    found := false
+    for _, bookmaker := range game.Bookmakers {
+        for _, market := range bookmaker.Markets {
+            if market.Key != entry.Market {
+                continue
+            }
+            for _, other := range market.Outcomes {
+                if !oppositeOutcome(entry.Market, outcome, other) {
+                    continue
+                }
+                if !found || americanToDecimal(other.Price) > americanToDecimal(best.Price) {
+                    best, bestBook, found = other, bookmaker, true
+                }
+            }
+        }
+    }
+    return bestBook, best, found
+}
+
+// planHedge prices an open position at the model's probability and, if the
+// other side is quoted, the stakes that hedge it. cashOut is the book's
+// offer for the position, or 0 if none was entered.
+func planHedge(entry LedgerEntry, game Game, prob float64, phase string, cashOut float64) hedgePlan {
+    payout := entry.Stake * americanToDecimal(entry.Odds)
+    plan := hedgePlan{
+        Entry:     entry,
+        Phase:     phase,
+        ModelProb: prob,
+        FairValue: roundStake(prob * payout),
+    }
+    if cashOut > 0 {
+        plan.CashOut = cashOut
+        plan.CashOutEdge = roundStake(cashOut - plan.FairValue)
+    }
+
+    bookmaker, hedge, ok := bestHedge(entry, game)
+    if !ok {
+        return plan
+    }
+    decimal := americanToDecimal(hedge.Price)
+    plan.HedgeBookmaker = bookmaker.Key
+    plan.HedgeSelection = formatSelection(entry.Market, selectionName(hedge), hedge.Point)
+    plan.HedgeOdds = hedge.Price
+
+    lock := payout / decimal
+    plan.LockStake = roundStake(lock)
+    plan.LockedProfit = roundStake(payout - entry.Stake - lock)
+    plan.LockCost = roundStake(lock - (1-prob)*lock*decimal)
+
+    breakEven := entry.Stake / (decimal - 1)
+    if breakEven < lock {
+        plan.BreakEvenStake = roundStake(breakEven)
+        plan.BreakEvenProfit = roundStake(payout - entry.Stake - breakEven)
+    }
+    return plan
+}
+
+func displayHedgePlan(plan hedgePlan) {
+    entry := plan.Entry
+    fmt.Printf("\n%s  %s %+.0f  stake %.2f  (%s, %s)\n", entry.ID,
+        formatSelection(entry.Market, entry.Team, entry.Point), entry.Odds, entry.Stake, entry.Bookmaker, marketLabel(entry.Market))
+    fmt.Printf("Game: %s [%s]\n", entry.Game, plan.Phase)
+    fmt.Printf("Model Probability: %.1f%% (%.1f%% when placed)\n", plan.ModelProb*100, entry.ModelProb*100)
+    fmt.Printf("Fair Value: %.2f of a %.2f potential return\n", plan.FairValue, entry.Stake*americanToDecimal(entry.Odds))
+
+    if plan.HedgeBookmaker == "" {
+        fmt.Printf("Hedge: the other side isn't quoted at this line right now\n")
+    } else {
+        fmt.Printf("Hedge: %s %+.0f at %s\n", plan.HedgeSelection, plan.HedgeOdds, plan.HedgeBookmaker)
+        cost := fmt.Sprintf("gives up %.2f of expected value", plan.LockCost)
+        if plan.LockCost < 0 {
+            cost = fmt.Sprintf("adds %.2f of expected value", -plan.LockCost)
+        }
+        fmt.Printf("  Lock:       stake %.2f for %+.2f either way (%s)\n", plan.LockStake, plan.LockedProfit, cost)
+        if plan.BreakEvenStake > 0 {
+            fmt.Printf("  Break-even: stake %.2f for %+.2f if the bet wins, 0.00 if it loses\n",
+                plan.BreakEvenStake, plan.BreakEvenProfit)
+        }
+    }
+
+    if plan.CashOut > 0 {
+        verdict := "below fair value, let it ride"
+        if plan.CashOutEdge >= 0 {
+            verdict = "at or above fair value, take it"
+        }
+        fmt.Printf("Cash-out: %.2f offered, %+.2f vs fair value - %s\n", plan.CashOut, plan.CashOutEdge, verdict)
+        if plan.HedgeBookmaker != "" && plan.LockedProfit > plan.CashOut-entry.Stake {
+            fmt.Printf("  Hedging locks %+.2f, more than the cash-out's %+.2f\n", plan.LockedProfit, plan.CashOut-entry.Stake)
+        }
+    }
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+    "math"
+    "testing"
+)
+
+// hedgeGame quotes each market at two books, so the hedge has to find the
+// better price on the other side
+var hedgeGame = Game{
+    ID:       "g1",
+    HomeTeam: "Boston Celtics",
+    AwayTeam: "Miami Heat",
+    Bookmakers: []Bookmaker{
+        {Key: "fanduel", Markets: []Market{
+            {Key: "h2h", Outcomes: []Outcome{{Name: "Boston Celtics", Price: 140}, {Name: "Miami Heat", Price: -130}}},
+            {Key: "spreads", Outcomes: []Outcome{
+                {Name: "Boston Celtics", Price: -110, Point: -4.5},
+                {Name: "Miami Heat", Price: -105, Point: 5.5},
+            }},
+            {Key: "totals", Outcomes: []Outcome{{Name: "Over", Price: -110, Point: 221.5}, {Name: "Under", Price: 100, Point: 221.5}}},
+        }},
+        {Key: "draftkings", Markets: []Market{
+            {Key: "h2h", Outcomes: []Outcome{{Name: "Boston Celtics", Price: 150}, {Name: "Miami Heat", Price: -120}}},
+            {Key: "spreads", Outcomes: []Outcome{
+                {Name: "Boston Celtics", Price: -110, Point: -4.5},
+                {Name: "Miami Heat", Price: -110, Point: 4.5},
+            }},
+            {Key: "totals", Outcomes: []Outcome{{Name: "Over", Price: -105, Point: 222.5}, {Name: "Under", Price: -115, Point: 222.5}}},
+        }},
+    },
+}
+
+func TestPlanHedge(t *testing.T) {
+    tests := []struct {
+        name    string
+        entry   LedgerEntry
+        prob    float64
+        cashOut float64
+        want    hedgePlan
+    }{
+        {
+            // Paid 250 if it wins; the lock stake 250/1.8333 returns the
+            // same 13.64 either way, and 100/0.8333 = 120 gets the stake back
+            name:    "moneyline at the best price on the other team",
+            entry:   LedgerEntry{Market: "h2h", Team: "Boston Celtics", Odds: 150, Stake: 100},
+            prob:    0.45,
+            cashOut: 105,
+            want: hedgePlan{
+                ModelProb:       0.45,
+                FairValue:       112.5,
+                HedgeBookmaker:  "draftkings",
+                HedgeSelection:  "Miami Heat",
+                HedgeOdds:       -120,
+                LockStake:       136.36,
+                LockedProfit:    13.64,
+                BreakEvenStake:  120,
+                BreakEvenProfit: 30,
+                LockCost:        -1.14,
+                CashOut:         105,
+                CashOutEdge:     -7.5,
+            },
+        },
+        {
+            // Paid 210 if it covers, so the lock stake is 210/1.9091 = 110
+            name:  "spread only hedges at the same line",
+            entry: LedgerEntry{Market: "spreads", Team: "Boston Celtics", Point: -4.5, Odds: -110, Stake: 110},
+            prob:  0.6,
+            want: hedgePlan{
+                ModelProb:      0.6,
+                FairValue:      126,
+                HedgeBookmaker: "draftkings",
+                HedgeSelection: "Miami Heat +4.5",
+                HedgeOdds:      -110,
+                LockStake:      110,
+                LockedProfit:   -10,
+                LockCost:       26,
+            },
+        },
+        {
+            // A favourite pays less than the break-even stake would cost
+            name:  "total with no break-even stake",
+            entry: LedgerEntry{Market: "totals", Team: "Over", Point: 221.5, Odds: -200, Stake: 100},
+            prob:  0.7,
+            want: hedgePlan{
+                ModelProb:      0.7,
+                FairValue:      105,
+                HedgeBookmaker: "fanduel",
+                HedgeSelection: "Under 221.5",
+                HedgeOdds:      100,
+                LockStake:      75,
+                LockedProfit:   -25,
+                LockCost:       30,
+            },
+        },
+        {
+            name:    "no price on the other side",
+            entry:   LedgerEntry{Market: "spreads", Team: "Boston Celtics", Point: -6.5, Odds: 120, Stake: 50},
+            prob:    0.4,
+            cashOut: 60,
+            want:    hedgePlan{ModelProb: 0.4, FairValue: 44, CashOut: 60, CashOutEdge: 16},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := planHedge(tt.entry, hedgeGame, tt.prob, phasePreGame, tt.cashOut)
+            tt.want.Entry, tt.want.Phase = tt.entry, phasePreGame
+            if got != tt.want {
+                t.Errorf("planHedge =\n%+v\nwant\n%+v", got, tt.want)
+            }
+        })
+    }
+}
+
+// Whichever side wins, a lock stake leaves the same profit
+func TestLockStakeEqualisesProfit(t *testing.T) {
+    entry := LedgerEntry{Market: "h2h", Team: "Boston Celtics", Odds: 150, Stake: 100}
+    plan := planHedge(entry, hedgeGame, 0.5, phasePreGame, 0)
+    ifEntryWins := entry.Stake*americanToDecimal(entry.Odds) - entry.Stake - plan.LockStake
+    ifHedgeWins := plan.LockStake*americanToDecimal(plan.HedgeOdds) - plan.LockStake - entry.Stake
+    if math.Abs(ifEntryWins-ifHedgeWins) > 0.02 || math.Abs(ifEntryWins-plan.LockedProfit) > 0.02 {
+        t.Errorf("profit %.2f if the entry wins, %.2f if the hedge wins, locked %.2f", ifEntryWins, ifHedgeWins, plan.LockedProfit)
+    }
+}
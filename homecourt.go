@@ -0,0 +1,59 @@
+package main
+
+import "math"
+
+// VenueSplit is a team's record and scoring margin at home or on the road
+type VenueSplit struct {
+    Games     int     `json:"games"`
+    WinRate   float64 `json:"win_rate"`
+    NetRating float64 `json:"net_rating"`
+}
+
+// leagueHomeEdge is how far above even home teams win across the league,
+// falling back to home_court before any splits are known
+func leagueHomeEdge(stats map[string]TeamStats) float64 {
+    games, wins := 0, 0.0
+    for _, teamStats := range stats {
+        games += teamStats.Home.Games
+        wins += teamStats.Home.WinRate * float64(teamStats.Home.Games)
+    }
+    if games == 0 {
+        return config.HomeCourt
+    }
+    return wins/float64(games) - 0.5
+}
+
+// teamHomeEdge is how much more a team wins at home than on the road: half
+// the gap between its home and road win rates, averaged with half the gap
+// between its home and road net ratings turned into win probability, and
+// shrunk toward the league prior by home_court_prior_games pseudo-games.
+// The margin gap says more than the record over a short sample. The
+// smaller split sets the sample size.
+func teamHomeEdge(teamStats TeamStats, prior float64) float64 {
+    games := teamStats.Home.Games
+    if teamStats.Away.Games < games {
+        games = teamStats.Away.Games
+    }
+    if games == 0 {
+        return prior
+    }
+    winGap := (teamStats.Home.WinRate - teamStats.Away.WinRate) / 2
+    marginGap := (teamStats.Home.NetRating - teamStats.Away.NetRating) / 2
+    marginEdge := math.Erf(marginGap/(config.MarginStdDev*math.Sqrt2)) / 2
+    raw := (winGap + marginEdge) / 2
+    n := float64(games)
+    k := config.HomeCourtPriorGames
+    return (n*raw + k*prior) / (n + k)
+}
+
+// homeCourtEdge is the home team's win probability boost for game: the
+// home team's edge at home averaged with the visitor's drop on the road
+func homeCourtEdge(stats map[string]TeamStats, game Game) float64 {
+    prior := leagueHomeEdge(stats)
+    home, homeOK := stats[game.HomeTeam]
+    away, awayOK := stats[game.AwayTeam]
+    if !homeOK || !awayOK {
+        return prior
+    }
+    return (teamHomeEdge(home, prior) + teamHomeEdge(away, prior)) / 2
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math/rand"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// NetworkError means the request never got an HTTP response
+type NetworkError struct {
+    Err error
+}
+
+func (e *NetworkError) Error() string { return "network error: " + redact(e.Err.Error()) }
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// DecodeError means the response body was not the JSON we expected
+type DecodeError struct {
+    Err error
+}
+
+func (e *DecodeError) Error() string { return "error decoding response: " + e.Err.Error() }
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// AuthError means the API key was rejected
+type AuthError struct {
+    Status int
+    Body   string
+}
+
+func (e *AuthError) Error() string {
+    return fmt.Sprintf("authentication failed (%d): %s", e.Status, e.Body)
+}
+
+// QuotaError means the key is out of requests or being rate limited
+type QuotaError struct {
+    Status     int
+    RetryAfter time.Duration
+    Body       string
+}
+
+func (e *QuotaError) Error() string {
+    msg := fmt.Sprintf("quota exceeded (%d): %s", e.Status, e.Body)
+    if e.RetryAfter > 0 {
+        msg += fmt.Sprintf(" (retry after %s)", e.RetryAfter)
+    }
+    return msg
+}
+
+// StatusError is any other non-2xx response
+type StatusError struct {
+    Status int
+    Body   string
+}
+
+func (e *StatusError) Error() string {
+    return fmt.Sprintf("unexpected status %d: %s", e.Status, e.Body)
+}
+
+// oddsClient is the shared client for the-odds-api. Each attempt gets its own
+// timeout; 5xx, 429 and network failures are retried with jittered
+// exponential backoff, honoring Retry-After when the server sends it.
+type oddsClient struct {
+    http       *http.Client
+    keys       *keyRing
+    baseURL    string
+    timeout    time.Duration
+    maxRetries int
+    backoff    time.Duration
+    maxBackoff time.Duration
+}
+
+func newOddsClient(keys *keyRing) *oddsClient {
+    return &oddsClient{
+        http:       &http.Client{},
+        keys:       keys,
+        baseURL:    baseURL,
+        timeout:    config.HTTPTimeout,
+        maxRetries: config.HTTPRetries,
+        backoff:    config.HTTPBackoff,
+        maxBackoff: config.HTTPMaxBackoff,
+    }
+}
+
+// getJSON fetches path with query and decodes the response into v. source
+// picks the cache TTL for the response.
+func (c *oddsClient) getJSON(ctx context.Context, source, path string, query url.Values, v interface{}) error {
+    body, err := c.getCached(ctx, source, path, query)
+    if err != nil {
+        return err
+    }
+    if err := json.Unmarshal(body, v); err != nil {
+        return &DecodeError{Err: err}
+    }
+    return nil
+}
+
+// getCached serves fresh responses from the cache and revalidates stale ones
+// with If-None-Match / If-Modified-Since when upstream gave us validators
+func (c *oddsClient) getCached(ctx context.Context, source, path string, query url.Values) ([]byte, error) {
+    key := cacheKey(source, c.baseURL+path, query.Encode())
+    if body, ok := cache.lookup(source, key); ok {
+        return body, nil
+    }
+
+    stale, _ := cache.get(key)
+    resp, err := c.get(ctx, path, query, stale)
+    if err != nil {
+        return nil, err
+    }
+    if resp.notModified {
+        stale.FetchedAt = time.Now()
+        cache.put(key, stale)
+        return stale.Body, nil
+    }
+
+    cache.put(key, cacheEntry{
+        FetchedAt:    time.Now(),
+        ETag:         resp.etag,
+        LastModified: resp.lastModified,
+        Body:         resp.body,
+    })
+    return resp.body, nil
+}
+
+// clientResponse is a successful response or a 304 for a conditional request
+type clientResponse struct {
+    body         []byte
+    etag         string
+    lastModified string
+    notModified  bool
+}
+
+func (c *oddsClient) get(ctx context.Context, path string, query url.Values, stale cacheEntry) (clientResponse, error) {
+    var lastErr error
+    for attempt := 0; attempt <= c.maxRetries; attempt++ {
+        if attempt > 0 {
+            if err := sleepContext(ctx, c.retryDelay(attempt, lastErr)); err != nil {
+                return clientResponse{}, lastErr
+            }
+        }
+
+        resp, err := c.attempt(ctx, path, query, stale)
+        if err == nil {
+            return resp, nil
+        }
+        lastErr = err
+        if !c.retryable(err) || ctx.Err() != nil {
+            return clientResponse{}, err
+        }
+    }
+    return clientResponse{}, lastErr
+}
+
+func (c *oddsClient) attempt(ctx context.Context, path string, query url.Values, stale cacheEntry) (clientResponse, error) {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+
+    apiKey := c.keys.key()
+    params := url.Values{}
+    for k, v := range query {
+        params[k] = v
+    }
+    params.Set("apiKey", apiKey)
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+    if err != nil {
+        return clientResponse{}, redactError(err)
+    }
+    if stale.ETag != "" {
+        req.Header.Set("If-None-Match", stale.ETag)
+    }
+    if stale.LastModified != "" {
+        req.Header.Set("If-Modified-Since", stale.LastModified)
+    }
+
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return clientResponse{}, &NetworkError{Err: err}
+    }
+    defer resp.Body.Close()
+    c.keys.observe(apiKey, resp)
+
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return clientResponse{}, &NetworkError{Err: err}
+    }
+
+    if resp.StatusCode == http.StatusNotModified && stale.Body != nil {
+        return clientResponse{notModified: true}, nil
+    }
+    if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+        return clientResponse{
+            body:         body,
+            etag:         resp.Header.Get("ETag"),
+            lastModified: resp.Header.Get("Last-Modified"),
+        }, nil
+    }
+
+    message := strings.TrimSpace(string(body))
+    if len(message) > 200 {
+        message = message[:200]
+    }
+    switch {
+    case resp.StatusCode == http.StatusTooManyRequests:
+        return clientResponse{}, &QuotaError{
+            Status:     resp.StatusCode,
+            RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+            Body:       message,
+        }
+    case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+        // The odds API answers 401 once a key's monthly quota is used up
+        if resp.Header.Get("x-requests-remaining") == "0" {
+            return clientResponse{}, &QuotaError{Status: resp.StatusCode, Body: message}
+        }
+        c.keys.markExhausted(apiKey)
+        return clientResponse{}, &AuthError{Status: resp.StatusCode, Body: message}
+    default:
+        return clientResponse{}, &StatusError{Status: resp.StatusCode, Body: message}
+    }
+}
+
+// retryable reports whether another attempt could succeed. Quota and auth
+// failures only make sense to retry when another key is available.
+func (c *oddsClient) retryable(err error) bool {
+    var netErr *NetworkError
+    var statusErr *StatusError
+    var quotaErr *QuotaError
+    var authErr *AuthError
+    switch {
+    case errors.As(err, &netErr):
+        return true
+    case errors.As(err, &statusErr):
+        return statusErr.Status >= 500
+    case errors.As(err, &quotaErr):
+        return quotaErr.Status == http.StatusTooManyRequests || c.keys.size() > 1
+    case errors.As(err, &authErr):
+        return c.keys.size() > 1
+    }
+    return false
+}
+
+// retryDelay is full-jitter exponential backoff, or the server's Retry-After
+func (c *oddsClient) retryDelay(attempt int, lastErr error) time.Duration {
+    var quotaErr *QuotaError
+    if errors.As(lastErr, &quotaErr) && quotaErr.RetryAfter > 0 {
+        return quotaErr.RetryAfter
+    }
+
+    delay := c.backoff << uint(attempt-1)
+    if delay > c.maxBackoff || delay <= 0 {
+        delay = c.maxBackoff
+    }
+    return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter accepts both delta-seconds and HTTP-date forms
+func parseRetryAfter(value string) time.Duration {
+    if value == "" {
+        return 0
+    }
+    if seconds, err := strconv.Atoi(value); err == nil {
+        return time.Duration(seconds) * time.Second
+    }
+    if when, err := http.ParseTime(value); err == nil {
+        if d := time.Until(when); d > 0 {
+            return d
+        }
+    }
+    return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
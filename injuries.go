@@ -0,0 +1,225 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "os"
+    "sort"
+    "strings"
+    "unicode"
+)
+
+// Injury is one player's availability for their team's next game
+type Injury struct {
+    Player string `json:"player"`
+    Team   string `json:"team"`
+    Status string `json:"status"`
+    Note   string `json:"note,omitempty"`
+}
+
+// PlayerStats is a player's season line from nba_stats_fetcher.py players
+type PlayerStats struct {
+    Player    string  `json:"player"`
+    Team      string  `json:"team"`
+    Games     int     `json:"games"`
+    Minutes   float64 `json:"minutes"`
+    PlusMinus float64 `json:"plus_minus"`
+}
+
+// InjuryProvider supplies the current injury report
+type InjuryProvider interface {
+    Name() string
+    Injuries(ctx context.Context) ([]Injury, error)
+}
+
+// fileInjuryProvider reads a JSON array of injuries from disk
+type fileInjuryProvider struct {
+    path string
+}
+
+func (p fileInjuryProvider) Name() string { return "file" }
+
+func (p fileInjuryProvider) Injuries(ctx context.Context) ([]Injury, error) {
+    data, err := os.ReadFile(p.path)
+    if err != nil {
+        return nil, fmt.Errorf("error reading injury file: %v", err)
+    }
+    return decodeInjuries(data)
+}
+
+// httpInjuryProvider fetches the same JSON array from a URL
+type httpInjuryProvider struct {
+    url    string
+    client *http.Client
+}
+
+func (p httpInjuryProvider) Name() string { return "http" }
+
+func (p httpInjuryProvider) Injuries(ctx context.Context) ([]Injury, error) {
+    ctx, cancel := context.WithTimeout(ctx, config.HTTPTimeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+    if err != nil {
+        return nil, fmt.Errorf("error creating injury request: %v", err)
+    }
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return nil, &NetworkError{Err: err}
+    }
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, &NetworkError{Err: err}
+    }
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return nil, &StatusError{Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+    }
+    return decodeInjuries(body)
+}
+
+func decodeInjuries(data []byte) ([]Injury, error) {
+    var injuries []Injury
+    if err := json.Unmarshal(data, &injuries); err != nil {
+        return nil, &DecodeError{Err: err}
+    }
+    for i := range injuries {
+        injuries[i].Team = canonicalTeamName(injuries[i].Team)
+        injuries[i].Status = strings.ToLower(strings.TrimSpace(injuries[i].Status))
+    }
+    return injuries, nil
+}
+
+// newInjuryProvider picks the configured provider, nil if none is set
+func newInjuryProvider() InjuryProvider {
+    switch {
+    case config.InjuryURL != "":
+        return httpInjuryProvider{url: config.InjuryURL, client: &http.Client{}}
+    case config.InjuryFile != "":
+        return fileInjuryProvider{path: config.InjuryFile}
+    }
+    return nil
+}
+
+func fetchPlayers(ctx context.Context) ([]PlayerStats, error) {
+    var combinedData CombinedData
+    if err := runStatsFetcher(ctx, "players", &combinedData); err != nil {
+        return nil, err
+    }
+    if len(combinedData.Players) == 0 {
+        return nil, fmt.Errorf("Python script returned no player stats")
+    }
+    return combinedData.Players, nil
+}
+
+// missProbability is the chance a player with this status sits out
+func missProbability(status string) float64 {
+    switch status {
+    case "out", "suspended", "inactive":
+        return 1
+    case "doubtful":
+        return 0.75
+    case "questionable", "day-to-day", "gtd":
+        return 0.5
+    case "probable":
+        return 0.1
+    }
+    return 0
+}
+
+// Players below these thresholds have too little sample to rate
+const (
+    minImpactGames   = 5
+    minImpactMinutes = 10
+)
+
+// playerImpact is the points of margin per game a team loses without the
+// player: on-court plus-minus per 48 over a replacement player's, weighted by
+// share of the minutes and split across the five players sharing the floor.
+// Negative impacts are ignored.
+func playerImpact(player PlayerStats) float64 {
+    if player.Games < minImpactGames || player.Minutes < minImpactMinutes {
+        return 0
+    }
+    per48 := player.PlusMinus / player.Minutes * 48
+    impact := (per48 - config.InjuryReplacementLevel) * player.Minutes / 48 / 5
+    if impact < 0 {
+        return 0
+    }
+    return impact
+}
+
+// normalizePlayerName drops case, spaces and punctuation so "P.J. Washington"
+// and "PJ Washington" match
+func normalizePlayerName(name string) string {
+    var b strings.Builder
+    for _, r := range strings.ToLower(name) {
+        if unicode.IsLetter(r) || unicode.IsDigit(r) {
+            b.WriteRune(r)
+        }
+    }
+    return b.String()
+}
+
+// applyInjuries returns a copy of stats with each team's expected points of
+// margin lost to injuries taken off its scoring and recorded in InjuryImpact
+func applyInjuries(stats map[string]TeamStats, injuries []Injury, players []PlayerStats) map[string]TeamStats {
+    if len(injuries) == 0 || len(players) == 0 {
+        return stats
+    }
+
+    byName := make(map[string]PlayerStats)
+    for _, player := range players {
+        player.Team = canonicalTeamName(player.Team)
+        byName[normalizePlayerName(player.Player)+"|"+player.Team] = player
+    }
+
+    adjusted := make(map[string]TeamStats, len(stats))
+    for name, teamStats := range stats {
+        adjusted[name] = teamStats
+    }
+
+    for _, injury := range injuries {
+        miss := missProbability(injury.Status)
+        player, ok := byName[normalizePlayerName(injury.Player)+"|"+injury.Team]
+        if miss == 0 || !ok {
+            continue
+        }
+        impact := playerImpact(player) * miss
+        if impact == 0 {
+            continue
+        }
+
+        // Stats are keyed by whatever name the stats source uses
+        for name, teamStats := range adjusted {
+            if canonicalTeamName(name) != injury.Team {
+                continue
+            }
+            teamStats.InjuryImpact += impact
+            teamStats.AvgPointsFor -= impact
+            teamStats.Unavailable = append(teamStats.Unavailable, fmt.Sprintf("%s (%s)", player.Player, injury.Status))
+            sort.Strings(teamStats.Unavailable)
+            adjusted[name] = teamStats
+        }
+    }
+    return adjusted
+}
+
+// injuryAdjustments turns the two teams' injury losses into team's win
+// probability shift
+func injuryAdjustments(stats map[string]TeamStats, game Game, team string) []Adjustment {
+    opponent := game.HomeTeam
+    if team == game.HomeTeam {
+        opponent = game.AwayTeam
+    }
+    shift := (stats[opponent].InjuryImpact - stats[team].InjuryImpact) * config.PointValue
+    if math.Abs(shift) < minAdjustment {
+        return nil
+    }
+    return []Adjustment{{Factor: "injuries", Value: shift}}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+    "fmt"
+    "math"
+)
+
+// kellyFraction is the share of bankroll the Kelly criterion stakes on a bet
+// that wins with prob at americanOdds, or 0 if the bet has no edge
+func kellyFraction(prob, americanOdds float64) float64 {
+    b := americanToDecimal(americanOdds) - 1
+    if b <= 0 {
+        return 0
+    }
+    return math.Max(0, (b*prob-(1-prob))/b)
+}
+
+// sizeStakes sets each bet's Kelly fraction and its suggested stake:
+// kelly_fraction of full Kelly, capped at max_stake of the bankroll
+func sizeStakes(valueBets []ValueBet) {
+    for i := range valueBets {
+        bet := &valueBets[i]
+        bet.Kelly = kellyFraction(bet.HistoricalProb, bet.Odds)
+        stake := math.Min(bet.Kelly*config.KellyFraction, config.MaxStake)
+        bet.Stake = roundStake(stake * config.Bankroll)
+    }
+}
+
+// roundStake rounds a stake to cents
+func roundStake(stake float64) float64 {
+    return math.Round(stake*100) / 100
+}
+
+// formatStake describes a bet's suggested stake against the bankroll
+func formatStake(bet ValueBet) string {
+    if bet.Kelly == 0 {
+        return "none (no Kelly edge)"
+    }
+    stake := fmt.Sprintf("%.2f (%.1f%% of bankroll, full Kelly %.1f%%)",
+        bet.Stake, bet.Stake/config.Bankroll*100, bet.Kelly*100)
+    if bet.StakeNote != "" {
+        stake += " - " + bet.StakeNote
+    }
+    return stake
+}
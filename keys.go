@@ -0,0 +1,312 @@
+package main
+
+import (
+    "context"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/pbkdf2"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+)
+
+// secretRegistry remembers every credential so it can be scrubbed from output
+type secretRegistry struct {
+    mu      sync.RWMutex
+    secrets []string
+}
+
+var secrets = &secretRegistry{}
+
+func registerSecret(secret string) {
+    // Very short values would redact unrelated text
+    if len(secret) < 6 {
+        return
+    }
+    secrets.mu.Lock()
+    defer secrets.mu.Unlock()
+    for _, s := range secrets.secrets {
+        if s == secret {
+            return
+        }
+    }
+    secrets.secrets = append(secrets.secrets, secret)
+}
+
+// redact replaces every registered secret in s
+func redact(s string) string {
+    secrets.mu.RLock()
+    defer secrets.mu.RUnlock()
+    for _, secret := range secrets.secrets {
+        s = strings.ReplaceAll(s, secret, redactedKey(secret))
+    }
+    return s
+}
+
+// redactedKey keeps the last four characters so keys can still be told apart
+func redactedKey(key string) string {
+    if len(key) <= 8 {
+        return "***"
+    }
+    return "***" + key[len(key)-4:]
+}
+
+// redactError returns err with any registered secret removed from its message
+func redactError(err error) error {
+    if err == nil {
+        return nil
+    }
+    return errors.New(redact(err.Error()))
+}
+
+// redactingWriter scrubs secrets from everything written through it
+type redactingWriter struct {
+    w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+    if _, err := io.WriteString(r.w, redact(string(p))); err != nil {
+        return 0, err
+    }
+    return len(p), nil
+}
+
+// keyRing rotates requests across several odds API keys so they share quota
+type keyRing struct {
+    source string
+
+    mu        sync.Mutex
+    keys      []string
+    next      int
+    exhausted map[string]bool
+}
+
+func newKeyRing(source string, keys []string) (*keyRing, error) {
+    var cleaned []string
+    for _, key := range keys {
+        if key = strings.TrimSpace(key); key != "" && !strings.HasPrefix(key, "#") {
+            cleaned = append(cleaned, key)
+            registerSecret(key)
+        }
+    }
+    if len(cleaned) == 0 {
+        return nil, fmt.Errorf("no API keys found in %s", source)
+    }
+    return &keyRing{
+        source:    source,
+        keys:      cleaned,
+        exhausted: make(map[string]bool),
+    }, nil
+}
+
+// key returns the next key with quota left, falling back to plain round robin
+// once every key has been marked exhausted
+func (k *keyRing) key() string {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+
+    for i := 0; i < len(k.keys); i++ {
+        key := k.keys[(k.next+i)%len(k.keys)]
+        if !k.exhausted[key] {
+            k.next = (k.next + i + 1) % len(k.keys)
+            return key
+        }
+    }
+    key := k.keys[k.next]
+    k.next = (k.next + 1) % len(k.keys)
+    return key
+}
+
+func (k *keyRing) markExhausted(key string) {
+    k.mu.Lock()
+    k.exhausted[key] = true
+    k.mu.Unlock()
+}
+
+// observe records the remaining quota the odds API reports for key
+func (k *keyRing) observe(key string, resp *http.Response) {
+    if resp.Header.Get("x-requests-remaining") == "0" {
+        k.markExhausted(key)
+    }
+}
+
+func (k *keyRing) size() int {
+    return len(k.keys)
+}
+
+// resolveAPIKeys finds odds API keys, in order of preference:
+// NBA_ODDS_API_KEYS / NBA_ODDS_API_KEY, the encrypted_key_file config key
+// (decrypted with NBA_KEY_PASSPHRASE), the api_key_file config key, and
+// finally the legacy api.txt.
+func resolveAPIKeys() (*keyRing, error) {
+    if raw := os.Getenv("NBA_ODDS_API_KEYS"); raw != "" {
+        return newKeyRing("NBA_ODDS_API_KEYS", strings.Split(raw, ","))
+    }
+    if raw := os.Getenv("NBA_ODDS_API_KEY"); raw != "" {
+        return newKeyRing("NBA_ODDS_API_KEY", []string{raw})
+    }
+
+    if config.EncryptedKeyFile != "" {
+        passphrase := os.Getenv("NBA_KEY_PASSPHRASE")
+        if passphrase == "" {
+            return nil, fmt.Errorf("NBA_KEY_PASSPHRASE must be set to decrypt %s", config.EncryptedKeyFile)
+        }
+        keys, err := readEncryptedKeyFile(config.EncryptedKeyFile, passphrase)
+        if err != nil {
+            return nil, err
+        }
+        return newKeyRing(config.EncryptedKeyFile, keys)
+    }
+
+    if config.APIKeyFile != "" {
+        data, err := os.ReadFile(config.APIKeyFile)
+        if err != nil {
+            return nil, fmt.Errorf("error reading %s: %v", config.APIKeyFile, err)
+        }
+        return newKeyRing(config.APIKeyFile, strings.Split(string(data), "\n"))
+    }
+
+    apiKey, err := loadAPIKey()
+    if err != nil {
+        return nil, err
+    }
+    return newKeyRing("api.txt", strings.Split(apiKey, "\n"))
+}
+
+// encryptedKeyFile is the on-disk format written by `keys encrypt`: the key
+// list encrypted with AES-256-GCM under a PBKDF2-SHA256 derived key
+type encryptedKeyFile struct {
+    Version    int    `json:"version"`
+    KDF        string `json:"kdf"`
+    Iterations int    `json:"iterations"`
+    Salt       []byte `json:"salt"`
+    Nonce      []byte `json:"nonce"`
+    Ciphertext []byte `json:"ciphertext"`
+}
+
+const keyFileIterations = 600000
+
+func keyFileCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+    key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+    if err != nil {
+        return nil, err
+    }
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+func writeEncryptedKeyFile(path string, keys []string, passphrase string) error {
+    salt := make([]byte, 16)
+    if _, err := rand.Read(salt); err != nil {
+        return err
+    }
+    aead, err := keyFileCipher(passphrase, salt, keyFileIterations)
+    if err != nil {
+        return err
+    }
+    nonce := make([]byte, aead.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return err
+    }
+
+    data, err := json.MarshalIndent(encryptedKeyFile{
+        Version:    1,
+        KDF:        "pbkdf2-sha256",
+        Iterations: keyFileIterations,
+        Salt:       salt,
+        Nonce:      nonce,
+        Ciphertext: aead.Seal(nil, nonce, []byte(strings.Join(keys, "\n")), nil),
+    }, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, data, 0600)
+}
+
+func readEncryptedKeyFile(path, passphrase string) ([]string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("error reading key file: %v", err)
+    }
+
+    var file encryptedKeyFile
+    if err := json.Unmarshal(data, &file); err != nil {
+        return nil, fmt.Errorf("error parsing key file %s: %v", path, err)
+    }
+    if file.Version != 1 || file.KDF != "pbkdf2-sha256" {
+        return nil, fmt.Errorf("unsupported key file format in %s", path)
+    }
+
+    aead, err := keyFileCipher(passphrase, file.Salt, file.Iterations)
+    if err != nil {
+        return nil, err
+    }
+    plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+    if err != nil {
+        return nil, fmt.Errorf("could not decrypt %s: wrong passphrase or corrupted file", path)
+    }
+    return strings.Split(string(plaintext), "\n"), nil
+}
+
+func runKeys(ctx context.Context, args []string) error {
+    if len(args) == 0 || (args[0] != "list" && args[0] != "encrypt") {
+        fmt.Fprintf(os.Stderr, "Usage: %s keys list|encrypt [flags]\n", progName())
+        return usageError{}
+    }
+
+    if args[0] == "encrypt" {
+        fs, configFlags := newFlagSet("keys encrypt",
+            "Encrypts a file of API keys (one per line) with the passphrase in NBA_KEY_PASSPHRASE.\n"+
+                "Point encrypted_key_file at the output to use it.")
+        in := fs.String("in", "", "plaintext key file to encrypt (required)")
+        out := fs.String("out", "keys.enc", "encrypted key file to write")
+        if err := parseFlags(fs, configFlags, args[1:]); err != nil {
+            return err
+        }
+        if *in == "" {
+            return usageError{"-in is required"}
+        }
+        passphrase := os.Getenv("NBA_KEY_PASSPHRASE")
+        if passphrase == "" {
+            return usageError{"NBA_KEY_PASSPHRASE must be set"}
+        }
+
+        data, err := os.ReadFile(*in)
+        if err != nil {
+            return fmt.Errorf("error reading %s: %v", *in, err)
+        }
+        ring, err := newKeyRing(*in, strings.Split(string(data), "\n"))
+        if err != nil {
+            return err
+        }
+        if err := writeEncryptedKeyFile(*out, ring.keys, passphrase); err != nil {
+            return fmt.Errorf("error writing %s: %v", *out, err)
+        }
+        fmt.Printf("Encrypted %d keys to %s\n", ring.size(), *out)
+        return nil
+    }
+
+    fs, configFlags := newFlagSet("keys list", "Shows where API keys are loaded from, with the keys redacted.")
+    if err := parseFlags(fs, configFlags, args[1:]); err != nil {
+        return err
+    }
+    ring, err := resolveAPIKeys()
+    if err != nil {
+        return err
+    }
+    fmt.Printf("%d keys from %s:\n", ring.size(), ring.source)
+    for _, key := range ring.keys {
+        fmt.Printf("- %s\n", redactedKey(key))
+    }
+    return nil
+}
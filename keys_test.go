@@ -0,0 +1,219 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// withSecrets gives a test an empty secret registry
+func withSecrets(t *testing.T) {
+    t.Helper()
+    saved := secrets.secrets
+    secrets.secrets = nil
+    t.Cleanup(func() { secrets.secrets = saved })
+}
+
+func TestEncryptedKeyFileRoundTrip(t *testing.T) {
+    keys := []string{"0123456789abcdef", "fedcba9876543210"}
+    path := filepath.Join(t.TempDir(), "keys.enc")
+    if err := writeEncryptedKeyFile(path, keys, "correct horse"); err != nil {
+        t.Fatalf("writeEncryptedKeyFile: %v", err)
+    }
+
+    info, err := os.Stat(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if mode := info.Mode().Perm(); mode != 0600 {
+        t.Errorf("key file mode = %o, want 600", mode)
+    }
+    data, _ := os.ReadFile(path)
+    for _, key := range keys {
+        if bytes.Contains(data, []byte(key)) {
+            t.Errorf("key file holds %s in plain text", key)
+        }
+    }
+
+    got, err := readEncryptedKeyFile(path, "correct horse")
+    if err != nil {
+        t.Fatalf("readEncryptedKeyFile: %v", err)
+    }
+    if !reflect.DeepEqual(got, keys) {
+        t.Errorf("keys = %q, want %q", got, keys)
+    }
+}
+
+func TestReadEncryptedKeyFileErrors(t *testing.T) {
+    dir := t.TempDir()
+    good := filepath.Join(dir, "keys.enc")
+    if err := writeEncryptedKeyFile(good, []string{"0123456789abcdef"}, "correct horse"); err != nil {
+        t.Fatal(err)
+    }
+    data, _ := os.ReadFile(good)
+    var file encryptedKeyFile
+    if err := json.Unmarshal(data, &file); err != nil {
+        t.Fatal(err)
+    }
+
+    // rewrite saves a copy of the good file changed by edit
+    rewrite := func(name string, edit func(f *encryptedKeyFile)) string {
+        changed := file
+        changed.Ciphertext = append([]byte(nil), file.Ciphertext...)
+        edit(&changed)
+        data, _ := json.Marshal(changed)
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, data, 0600); err != nil {
+            t.Fatal(err)
+        }
+        return path
+    }
+    garbage := filepath.Join(dir, "garbage")
+    os.WriteFile(garbage, []byte("not json"), 0600)
+
+    tests := []struct {
+        name, path, passphrase, want string
+    }{
+        {"wrong passphrase", good, "wrong horse", "wrong passphrase or corrupted file"},
+        {"tampered ciphertext", rewrite("tampered", func(f *encryptedKeyFile) { f.Ciphertext[0] ^= 1 }), "correct horse", "wrong passphrase or corrupted file"},
+        {"unknown version", rewrite("version", func(f *encryptedKeyFile) { f.Version = 2 }), "correct horse", "unsupported key file format"},
+        {"unknown kdf", rewrite("kdf", func(f *encryptedKeyFile) { f.KDF = "scrypt" }), "correct horse", "unsupported key file format"},
+        {"not json", garbage, "correct horse", "error parsing key file"},
+        {"missing", filepath.Join(dir, "missing"), "correct horse", "error reading key file"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            keys, err := readEncryptedKeyFile(tt.path, tt.passphrase)
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Errorf("readEncryptedKeyFile = %q, %v, want an error containing %q", keys, err, tt.want)
+            }
+        })
+    }
+}
+
+func TestResolveAPIKeysFromEncryptedFile(t *testing.T) {
+    withSecrets(t)
+    path := filepath.Join(t.TempDir(), "keys.enc")
+    if err := writeEncryptedKeyFile(path, []string{"0123456789abcdef", "# spare", ""}, "correct horse"); err != nil {
+        t.Fatal(err)
+    }
+    withConfig(t, func(c *Config) { c.EncryptedKeyFile = path })
+    t.Setenv("NBA_ODDS_API_KEYS", "")
+    t.Setenv("NBA_ODDS_API_KEY", "")
+
+    t.Setenv("NBA_KEY_PASSPHRASE", "")
+    if _, err := resolveAPIKeys(); err == nil || !strings.Contains(err.Error(), "NBA_KEY_PASSPHRASE") {
+        t.Errorf("resolveAPIKeys without a passphrase = %v, want it asked for", err)
+    }
+
+    t.Setenv("NBA_KEY_PASSPHRASE", "correct horse")
+    ring, err := resolveAPIKeys()
+    if err != nil {
+        t.Fatalf("resolveAPIKeys: %v", err)
+    }
+    if ring.source != path || !reflect.DeepEqual(ring.keys, []string{"0123456789abcdef"}) {
+        t.Errorf("ring = %s %q, want the one key from %s", ring.source, ring.keys, path)
+    }
+    if got := redact("key=0123456789abcdef"); got != "key=***cdef" {
+        t.Errorf("decrypted key not registered for redaction: %q", got)
+    }
+
+    // The environment wins over the key file
+    t.Setenv("NBA_ODDS_API_KEY", "envkey123456")
+    if ring, err := resolveAPIKeys(); err != nil || ring.source != "NBA_ODDS_API_KEY" {
+        t.Errorf("resolveAPIKeys with NBA_ODDS_API_KEY set = %v, %v", ring, err)
+    }
+}
+
+func TestRedactedKey(t *testing.T) {
+    tests := []struct{ key, want string }{
+        {"", "***"},
+        {"abcd1234", "***"},
+        {"abcd12345", "***2345"},
+        {"0123456789abcdef", "***cdef"},
+    }
+    for _, tt := range tests {
+        if got := redactedKey(tt.key); got != tt.want {
+            t.Errorf("redactedKey(%q) = %q, want %q", tt.key, got, tt.want)
+        }
+    }
+}
+
+func TestRedact(t *testing.T) {
+    withSecrets(t)
+    registerSecret("0123456789abcdef")
+    registerSecret("0123456789abcdef")
+    registerSecret("short")
+    registerSecret("secret")
+    if len(secrets.secrets) != 2 {
+        t.Errorf("registered %q, want each long enough secret once", secrets.secrets)
+    }
+
+    tests := []struct{ in, want string }{
+        {"GET /odds?apiKey=0123456789abcdef&regions=us", "GET /odds?apiKey=***cdef&regions=us"},
+        {"0123456789abcdef and 0123456789abcdef", "***cdef and ***cdef"},
+        {"my secret is short", "my *** is short"},
+        {"nothing to hide", "nothing to hide"},
+    }
+    for _, tt := range tests {
+        if got := redact(tt.in); got != tt.want {
+            t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+
+    if err := redactError(errors.New("bad key 0123456789abcdef")); err.Error() != "bad key ***cdef" {
+        t.Errorf("redactError = %q", err)
+    }
+    if redactError(nil) != nil {
+        t.Error("redactError(nil) != nil")
+    }
+
+    var out bytes.Buffer
+    msg := "retrying with 0123456789abcdef\n"
+    n, err := redactingWriter{&out}.Write([]byte(msg))
+    if err != nil || n != len(msg) || out.String() != "retrying with ***cdef\n" {
+        t.Errorf("redactingWriter wrote %q, returned %d, %v", out.String(), n, err)
+    }
+}
+
+func TestKeyRingSkipsExhaustedKeys(t *testing.T) {
+    withSecrets(t)
+    ring, err := newKeyRing("test", []string{" key-one-1 ", "# comment", "key-two-2", "", "key-three-3"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if ring.size() != 3 {
+        t.Fatalf("ring = %q, want three keys", ring.keys)
+    }
+
+    var got []string
+    for i := 0; i < 3; i++ {
+        got = append(got, ring.key())
+    }
+    ring.markExhausted("key-two-2")
+    for i := 0; i < 3; i++ {
+        got = append(got, ring.key())
+    }
+    ring.markExhausted("key-one-1")
+    ring.markExhausted("key-three-3")
+    // With every key exhausted it falls back to plain round robin
+    got = append(got, ring.key(), ring.key())
+
+    want := []string{
+        "key-one-1", "key-two-2", "key-three-3",
+        "key-one-1", "key-three-3", "key-one-1",
+        "key-two-2", "key-three-3",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("keys = %q, want %q", got, want)
+    }
+
+    if _, err := newKeyRing("empty", []string{"", "# nothing"}); err == nil {
+        t.Error("newKeyRing accepted a file with no keys")
+    }
+}
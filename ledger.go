@@ -0,0 +1,274 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "os"
+    "time"
+)
+
+const (
+    betOpen = "open"
+    betWon  = "won"
+    betLost = "lost"
+    betPush = "push"
+)
+
+// LedgerEntry is one recorded bet. The ledger is an append-friendly JSON
+// lines file so it can be inspected and edited by hand.
+type LedgerEntry struct {
+    ID           string    `json:"id"`
+    PlacedAt     time.Time `json:"placed_at"`
+    GameID       string    `json:"game_id"`
+    Game         string    `json:"game"`
+    CommenceTime time.Time `json:"commence_time,omitzero"`
+    Bookmaker    string    `json:"bookmaker"`
+    Market       string    `json:"market"`
+    Team         string    `json:"team"`
+    Point        float64   `json:"point,omitempty"`
+    Odds         float64   `json:"odds"`
+    Stake        float64   `json:"stake"`
+    ModelProb    float64   `json:"model_prob"`
+    Value        float64   `json:"value"`
+    Confidence   float64   `json:"confidence"`
+    Status       string    `json:"status"`
+    Payout       float64   `json:"payout"`
+    SettledAt    time.Time `json:"settled_at,omitzero"`
+}
+
+// newLedgerEntry records bet. Bets recorded together share now, so seq,
+// their position in the batch, keeps their IDs apart.
+func newLedgerEntry(bet ValueBet, stake float64, now time.Time, seq int) LedgerEntry {
+    return LedgerEntry{
+        ID:           fmt.Sprintf("%s-%s-%d-%d", bet.GameID, bet.Bookmaker, now.UnixNano(), seq),
+        PlacedAt:     now,
+        GameID:       bet.GameID,
+        Game:         bet.Game,
+        CommenceTime: bet.CommenceTime,
+        Bookmaker:    bet.Bookmaker,
+        Market:       bet.Market,
+        Team:         bet.Team,
+        Point:        bet.Point,
+        Odds:         bet.Odds,
+        Stake:        stake,
+        ModelProb:    bet.HistoricalProb,
+        Value:        bet.Value,
+        Confidence:   bet.Confidence,
+        Status:       betOpen,
+    }
+}
+
+// Profit is the net result of a settled bet, zero while open
+func (e LedgerEntry) Profit() float64 {
+    if e.Status == betOpen {
+        return 0
+    }
+    return e.Payout - e.Stake
+}
+
+// settle records the result and the amount returned (stake included)
+func (e *LedgerEntry) settle(status string, now time.Time) {
+    e.Status = status
+    e.SettledAt = now
+    switch status {
+    case betWon:
+        e.Payout = e.Stake * americanToDecimal(e.Odds)
+    case betPush:
+        e.Payout = e.Stake
+    default:
+        e.Payout = 0
+    }
+}
+
+func americanToDecimal(americanOdds float64) float64 {
+    if americanOdds > 0 {
+        return 1 + americanOdds/100
+    }
+    return 1 + 100/(-americanOdds)
+}
+
+// loadLedger reads every entry; a missing file is an empty ledger
+func loadLedger(path string) ([]LedgerEntry, error) {
+    file, err := os.Open(path)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("error reading ledger: %v", err)
+    }
+    defer file.Close()
+
+    var entries []LedgerEntry
+    scanner := bufio.NewScanner(file)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        if len(scanner.Bytes()) == 0 {
+            continue
+        }
+        var entry LedgerEntry
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+            return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+        }
+        entries = append(entries, entry)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("error reading ledger: %v", err)
+    }
+    return entries, nil
+}
+
+// saveLedger rewrites the ledger atomically
+func saveLedger(path string, entries []LedgerEntry) error {
+    tmp := path + ".tmp"
+    file, err := os.Create(tmp)
+    if err != nil {
+        return fmt.Errorf("error writing ledger: %v", err)
+    }
+
+    writer := bufio.NewWriter(file)
+    encoder := json.NewEncoder(writer)
+    for _, entry := range entries {
+        if err := encoder.Encode(entry); err != nil {
+            file.Close()
+            return fmt.Errorf("error writing ledger: %v", err)
+        }
+    }
+    if err := writer.Flush(); err != nil {
+        file.Close()
+        return fmt.Errorf("error writing ledger: %v", err)
+    }
+    if err := file.Close(); err != nil {
+        return fmt.Errorf("error writing ledger: %v", err)
+    }
+    return os.Rename(tmp, path)
+}
+
+func appendLedger(path string, newEntries []LedgerEntry) error {
+    file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if err != nil {
+        return fmt.Errorf("error writing ledger: %v", err)
+    }
+    defer file.Close()
+
+    encoder := json.NewEncoder(file)
+    for _, entry := range newEntries {
+        if err := encoder.Encode(entry); err != nil {
+            return fmt.Errorf("error writing ledger: %v", err)
+        }
+    }
+    return nil
+}
+
+// settleFromScores resolves open moneyline, spread and total bets on
+// completed games. Period markets and player props aren't in the scores
+// feed and are left for settle -id. It returns the number of entries it
+// settled.
+func settleFromScores(entries []LedgerEntry, scores []GameScore, now time.Time) int {
+    byID := make(map[string]GameScore)
+    for _, score := range scores {
+        if score.Completed {
+            byID[score.ID] = score
+        }
+    }
+
+    settled := 0
+    for i := range entries {
+        entry := &entries[i]
+        if entry.Status != betOpen {
+            continue
+        }
+        score, ok := byID[entry.GameID]
+        if !ok {
+            continue
+        }
+
+        result, ok := scoreResult(*entry, score)
+        if !ok {
+            continue
+        }
+        switch {
+        case result > 0:
+            entry.settle(betWon, now)
+        case result < 0:
+            entry.settle(betLost, now)
+        default:
+            entry.settle(betPush, now)
+        }
+        settled++
+    }
+    return settled
+}
+
+// scoreResult is positive when entry won on score's final and zero on a
+// push, or false if the final doesn't settle entry's market
+func scoreResult(entry LedgerEntry, score GameScore) (float64, bool) {
+    switch entry.Market {
+    case "h2h", "spreads":
+        teamScore, opponentScore, ok := score.pointsFor(entry.Team)
+        return float64(teamScore-opponentScore) + entry.Point, ok
+    case "totals":
+        homeScore, awayScore, ok := score.pointsFor(score.HomeTeam)
+        return overUnder(entry.Team, float64(homeScore+awayScore), entry.Point), ok
+    }
+    return 0, false
+}
+
+// ledgerSummary aggregates settled results
+type ledgerSummary struct {
+    Bets     int     `json:"bets"`
+    Open     int     `json:"open"`
+    Won      int     `json:"won"`
+    Lost     int     `json:"lost"`
+    Push     int     `json:"push"`
+    Staked   float64 `json:"staked"`
+    Profit   float64 `json:"profit"`
+    ModelWin float64 `json:"expected_wins"`
+}
+
+func summarizeLedger(entries []LedgerEntry) ledgerSummary {
+    var s ledgerSummary
+    for _, entry := range entries {
+        s.Bets++
+        switch entry.Status {
+        case betOpen:
+            s.Open++
+            continue
+        case betWon:
+            s.Won++
+        case betLost:
+            s.Lost++
+        case betPush:
+            s.Push++
+        }
+        s.Staked += entry.Stake
+        s.Profit += entry.Profit()
+        s.ModelWin += entry.ModelProb
+    }
+    return s
+}
+
+// ROI is profit over settled stake
+func (s ledgerSummary) ROI() float64 {
+    if s.Staked == 0 {
+        return 0
+    }
+    return s.Profit / s.Staked
+}
+
+func (s ledgerSummary) settled() int {
+    return s.Won + s.Lost + s.Push
+}
+
+func (s ledgerSummary) print(title string) {
+    fmt.Printf("\n%s:\n", title)
+    fmt.Printf("Bets: %d (%d open, %d won, %d lost, %d push)\n", s.Bets, s.Open, s.Won, s.Lost, s.Push)
+    if s.settled() == 0 {
+        fmt.Printf("No settled bets yet\n")
+        return
+    }
+    fmt.Printf("Staked: %.2f  Profit: %+.2f  ROI: %+.1f%%\n", s.Staked, s.Profit, s.ROI()*100)
+    fmt.Printf("Win Rate: %.1f%% (model expected %.1f%%)\n",
+        float64(s.Won)/float64(s.settled())*100, s.ModelWin/float64(s.settled())*100)
+}
@@ -1,30 +1,120 @@
 # nba_stats_fetcher.py
 from nba_api.live.nba.endpoints import scoreboard
-from nba_api.stats.endpoints import leaguedashteamstats
+from nba_api.stats.endpoints import leaguedashplayerstats, leaguedashteamstats, leaguegamelog
 import json
 import sys
 
+SEASON = '2023-24'
+
+RECENT_GAMES = 10
+
+_game_log = None
+
+def get_game_log():
+    # One row per team per completed game, shared by stats and schedule
+    global _game_log
+    if _game_log is None:
+        game_log = leaguegamelog.LeagueGameLog(
+            season=SEASON,
+            season_type_all_star='Regular Season',
+            player_or_team_abbreviation='T'
+        )
+
+        result = game_log.get_dict()['resultSets'][0]
+        headers = result['headers']
+        _game_log = [dict(zip(headers, row)) for row in result['rowSet']]
+    return _game_log
+
+def get_recent_games():
+    # Each team's last RECENT_GAMES results, most recent first
+    try:
+        rows = get_game_log()
+    except Exception as e:
+        print(f"Error in get_recent_games: {e}", file=sys.stderr)
+        return {}
+
+    names = {row['TEAM_ABBREVIATION']: row['TEAM_NAME'] for row in rows}
+    recent = {}
+    for row in sorted(rows, key=lambda r: r['GAME_DATE'], reverse=True):
+        games = recent.setdefault(row['TEAM_NAME'], [])
+        if len(games) >= RECENT_GAMES:
+            continue
+        opponent = row['MATCHUP'].split()[-1]
+        games.append({
+            'game_date': str(row['GAME_DATE'])[:10],
+            'opponent': names.get(opponent, opponent),
+            'home': ' vs. ' in row['MATCHUP'],
+            'margin': float(row['PLUS_MINUS']),
+            'won': row['WL'] == 'W'
+        })
+    return recent
+
+def get_venue_splits():
+    # Each team's record and scoring at home and on the road
+    try:
+        rows = get_game_log()
+    except Exception as e:
+        print(f"Error in get_venue_splits: {e}", file=sys.stderr)
+        return {}
+
+    totals = {}
+    for row in rows:
+        venue = 'home' if ' vs. ' in row['MATCHUP'] else 'away'
+        team = totals.setdefault(row['TEAM_NAME'], {})
+        split = team.setdefault(venue, {'games': 0, 'wins': 0, 'points_for': 0.0, 'points_against': 0.0})
+        points = float(row['PTS'])
+        split['games'] += 1
+        split['wins'] += 1 if row['WL'] == 'W' else 0
+        split['points_for'] += points
+        split['points_against'] += points - float(row['PLUS_MINUS'])
+
+    splits = {}
+    for team_name, venues in totals.items():
+        splits[team_name] = {}
+        for venue, split in venues.items():
+            games = split['games']
+            points_for = split['points_for'] / games
+            points_against = split['points_against'] / games
+            splits[team_name][venue] = {
+                'games': games,
+                'win_rate': split['wins'] / games,
+                'net_rating': points_for - points_against
+            }
+    return splits
+
 def get_team_stats():
     try:
         team_stats = leaguedashteamstats.LeagueDashTeamStats(
             per_mode_detailed='PerGame',
-            season='2023-24',
+            season=SEASON,
             season_type_all_star='Regular Season'
         )
         
-        stats_dict = team_stats.get_dict()
+        result = team_stats.get_dict()['resultSets'][0]
+        headers = result['headers']
+        recent = get_recent_games()
+        splits = get_venue_splits()
         processed_stats = {}
         
-        for row in stats_dict['resultSets'][0]['rowSet']:
-            team_name = f"{row[1]}"  # Full team name
-            wins = float(row[3])
-            losses = float(row[4])
+        for row in result['rowSet']:
+            # Look columns up by name; their positions change between seasons
+            record = dict(zip(headers, row))
+            team_name = record['TEAM_NAME']
+            wins = float(record['W'])
+            losses = float(record['L'])
+            points_for = float(record['PTS'])
+            recent_games = recent.get(team_name, [])
+            venues = splits.get(team_name, {})
             
             processed_stats[team_name] = {
                 'win_rate': wins / (wins + losses) if (wins + losses) > 0 else 0,
-                'avg_points_for': float(row[26]),
-                'avg_points_against': float(row[27]),
-                'last_ten_games': calculate_last_ten(wins, losses, row[11])
+                'avg_points_for': points_for,
+                # PLUS_MINUS is the per-game margin, so this is points allowed
+                'avg_points_against': points_for - float(record['PLUS_MINUS']),
+                'last_ten_games': [game['won'] for game in recent_games],
+                'recent_games': recent_games,
+                'home': venues.get('home', {}),
+                'away': venues.get('away', {})
             }
         
         return processed_stats
@@ -32,14 +122,83 @@ def get_team_stats():
         print(f"Error in get_team_stats: {e}", file=sys.stderr)
         return {}
 
-def calculate_last_ten(wins, losses, l10):
-    if not l10:
-        return [False] * 10
+def get_schedule():
+    # Every completed game, for rest, travel and team ratings
     try:
-        wins_l10 = int(str(l10).split('-')[0])
-        return [True] * wins_l10 + [False] * (10 - wins_l10)
-    except:
-        return [False] * 10
+        schedule = []
+        for record in get_game_log():
+            schedule.append({
+                'team': record['TEAM_NAME'],
+                'game_date': str(record['GAME_DATE'])[:10],
+                'matchup': record['MATCHUP'],
+                'points': float(record['PTS']),
+                # Standard box score estimate of possessions
+                'possessions': float(record['FGA']) + 0.44 * float(record['FTA'])
+                    - float(record['OREB']) + float(record['TOV'])
+            })
+
+        return schedule
+    except Exception as e:
+        print(f"Error in get_schedule: {e}", file=sys.stderr)
+        return []
+
+def get_players():
+    # Per-game minutes and plus-minus, for rating injured players
+    try:
+        player_stats = leaguedashplayerstats.LeagueDashPlayerStats(
+            per_mode_detailed='PerGame',
+            season=SEASON,
+            season_type_all_star='Regular Season'
+        )
+
+        result = player_stats.get_dict()['resultSets'][0]
+        headers = result['headers']
+        players = []
+        for row in result['rowSet']:
+            record = dict(zip(headers, row))
+            players.append({
+                'player': record['PLAYER_NAME'],
+                'team': record['TEAM_ABBREVIATION'],
+                'games': int(record['GP']),
+                'minutes': float(record['MIN']),
+                'plus_minus': float(record['PLUS_MINUS'])
+            })
+
+        return players
+    except Exception as e:
+        print(f"Error in get_players: {e}", file=sys.stderr)
+        return []
+
+def get_player_game_logs():
+    # One row per player per game, for player prop projections
+    try:
+        game_log = leaguegamelog.LeagueGameLog(
+            season=SEASON,
+            season_type_all_star='Regular Season',
+            player_or_team_abbreviation='P'
+        )
+
+        result = game_log.get_dict()['resultSets'][0]
+        headers = result['headers']
+        logs = []
+        for row in result['rowSet']:
+            record = dict(zip(headers, row))
+            logs.append({
+                'player': record['PLAYER_NAME'],
+                'team': record['TEAM_ABBREVIATION'],
+                'game_date': str(record['GAME_DATE'])[:10],
+                'matchup': record['MATCHUP'],
+                'minutes': float(record['MIN'] or 0),
+                'points': float(record['PTS'] or 0),
+                'rebounds': float(record['REB'] or 0),
+                'assists': float(record['AST'] or 0),
+                'threes': float(record['FG3M'] or 0)
+            })
+
+        return logs
+    except Exception as e:
+        print(f"Error in get_player_game_logs: {e}", file=sys.stderr)
+        return []
 
 def get_live_scores():
     try:
@@ -76,14 +235,24 @@ def get_live_scores():
 
 if __name__ == "__main__":
     try:
-        stats = get_team_stats()
-        live_scores = get_live_scores()
-        
+        # "stats", "live", "schedule", "players" or "gamelogs" fetch just one part so callers can cache them separately
+        mode = sys.argv[1] if len(sys.argv) > 1 else "all"
+        if mode not in ("all", "stats", "live", "schedule", "players", "gamelogs"):
+            print(f"Unknown mode {mode}, expected stats, live, schedule, players, gamelogs or all", file=sys.stderr)
+            sys.exit(2)
+
         # Output even if one of them is empty
-        output = {
-            'stats': stats,
-            'live_scores': live_scores
-        }
+        output = {}
+        if mode in ("all", "stats"):
+            output['stats'] = get_team_stats()
+        if mode in ("all", "live"):
+            output['live_scores'] = get_live_scores()
+        if mode in ("all", "schedule"):
+            output['schedule'] = get_schedule()
+        if mode in ("all", "players"):
+            output['players'] = get_players()
+        if mode in ("all", "gamelogs"):
+            output['game_logs'] = get_player_game_logs()
         
         print(json.dumps(output))
     except Exception as e:
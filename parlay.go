@@ -0,0 +1,309 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+)
+
+// Value bets considered as legs, best edge first; more makes the search slow
+const maxParlayCandidates = 20
+
+// Parlay is a combination of value bets at one bookmaker. Legs in the same
+// game are priced jointly from its simulation; legs in different games, and
+// player props, are independent. A same-game parlay has no Odds, EV or
+// stake: books price those below the product of the legs, so only its fair
+// odds are known until the book quotes it.
+type Parlay struct {
+    Legs            []ValueBet `json:"legs"`
+    Bookmaker       string     `json:"bookmaker"`
+    SameGame        bool       `json:"same_game"`
+    LegOdds         float64    `json:"leg_odds"`
+    Odds            float64    `json:"odds,omitempty"`
+    ImpliedProb     float64    `json:"implied_prob,omitempty"`
+    IndependentProb float64    `json:"independent_prob"`
+    JointProb       float64    `json:"joint_prob"`
+    Correlation     float64    `json:"correlation"`
+    FairOdds        float64    `json:"fair_odds"`
+    EV              float64    `json:"ev,omitempty"`
+    Kelly           float64    `json:"kelly,omitempty"`
+    Stake           float64    `json:"stake,omitempty"`
+    StakeNote       string     `json:"stake_note,omitempty"`
+}
+
+// parlayPricer prices parlay legs, simulating each game once
+type parlayPricer struct {
+    games      map[string]Game
+    teamStats  map[string]TeamStats
+    liveScores map[string]LiveGameState
+    schedule   teamSchedule
+    sims       map[string]*gameSim
+}
+
+func newParlayPricer(games []Game, teamStats map[string]TeamStats, liveScores map[string]LiveGameState, schedule teamSchedule) *parlayPricer {
+    byID := make(map[string]Game, len(games))
+    for _, game := range games {
+        byID[game.ID] = game
+    }
+    return &parlayPricer{
+        games:      byID,
+        teamStats:  teamStats,
+        liveScores: liveScores,
+        schedule:   schedule,
+        sims:       make(map[string]*gameSim),
+    }
+}
+
+// sim returns the simulation of the game with gameID, or nil if it can't be
+// simulated
+func (p *parlayPricer) sim(gameID string) (Game, *gameSim) {
+    game, ok := p.games[gameID]
+    if !ok {
+        return game, nil
+    }
+    if sim, done := p.sims[gameID]; done {
+        return game, sim
+    }
+    liveGame, isLive := p.liveScores[fmt.Sprintf("%s vs %s", game.AwayTeam, game.HomeTeam)]
+    inPlay := gamePhase(game, liveGame, isLive, game.asOf()) == phaseInPlay
+    sim := newGameSim(game, p.teamStats, p.schedule, liveGame, inPlay)
+    p.sims[gameID] = sim
+    return game, sim
+}
+
+// correlation is how much likelier legs, all from one game, are to win
+// together than if they were independent, counted over the game's
+// simulated runs. Runs where any leg pushes are left out, as they are for
+// the legs' own probabilities.
+func (p *parlayPricer) correlation(legs []ValueBet) float64 {
+    game, sim := p.sim(legs[0].GameID)
+    if sim == nil {
+        return 1
+    }
+    settlers := make([]func(run simRun) float64, len(legs))
+    for i, leg := range legs {
+        settle, ok := sim.settler(game, leg.Market, betOutcome(leg), 0, 0)
+        if !ok {
+            return 1
+        }
+        settlers[i] = settle
+    }
+
+    wins, runs := 0, 0
+    legWins := make([]int, len(legs))
+    results := make([]float64, len(legs))
+    for _, run := range sim.Runs {
+        pushed := false
+        for i, settle := range settlers {
+            results[i] = settle(run)
+            pushed = pushed || results[i] == 0
+        }
+        if pushed {
+            continue
+        }
+        runs++
+        all := true
+        for i, result := range results {
+            if result > 0 {
+                legWins[i]++
+            } else {
+                all = false
+            }
+        }
+        if all {
+            wins++
+        }
+    }
+    if runs == 0 {
+        return 1
+    }
+
+    independent := 1.0
+    for _, w := range legWins {
+        independent *= float64(w) / float64(runs)
+    }
+    if independent == 0 {
+        return 1
+    }
+    return float64(wins) / float64(runs) / independent
+}
+
+// price fills in a parlay's probabilities and, unless it's a same-game
+// parlay, its price and value from its legs
+func (p *parlayPricer) price(legs []ValueBet) Parlay {
+    parlay := Parlay{Legs: legs, Bookmaker: legs[0].Bookmaker, Correlation: 1}
+    decimal := 1.0
+    games := make(map[string]bool)
+    byGame := make(map[string][]ValueBet)
+    parlay.IndependentProb = 1
+    minProb := 1.0
+    for _, leg := range legs {
+        decimal *= americanToDecimal(leg.Odds)
+        parlay.IndependentProb *= leg.HistoricalProb
+        minProb = math.Min(minProb, leg.HistoricalProb)
+        parlay.SameGame = parlay.SameGame || games[leg.GameID]
+        games[leg.GameID] = true
+        // Props aren't simulated, so they count as independent of the rest
+        if _, isProp := propStats[leg.Market]; !isProp {
+            byGame[leg.GameID] = append(byGame[leg.GameID], leg)
+        }
+    }
+    for _, gameLegs := range byGame {
+        if len(gameLegs) > 1 {
+            parlay.Correlation *= p.correlation(gameLegs)
+        }
+    }
+
+    // The legs keep the model's own probabilities; the simulation only
+    // supplies how they move together
+    parlay.JointProb = math.Min(parlay.IndependentProb*parlay.Correlation, minProb)
+    parlay.LegOdds = decimalToAmerican(decimal)
+    parlay.FairOdds = decimalToAmerican(1 / parlay.JointProb)
+    if parlay.SameGame {
+        return parlay
+    }
+    parlay.Odds = parlay.LegOdds
+    parlay.ImpliedProb = 1 / decimal
+    parlay.EV = parlay.JointProb*decimal - 1
+    parlay.Kelly = kellyFraction(parlay.JointProb, parlay.Odds)
+    return parlay
+}
+
+// buildParlays combines value bets into two- and up to maxLegs-leg parlays
+// at the same bookmaker, never two legs on one market of one game, and
+// stakes the best by expected value until budget runs out, each trimmed so
+// that with the open bets no game, team or day its legs touch goes over its
+// exposure cap. Same-game
+// parlays can't be valued without the book's quote, so they're returned
+// apart, unstaked, best joint probability against the legs' odds first.
+func buildParlays(valueBets []ValueBet, maxLegs int, budget float64, pricer *parlayPricer, open []LedgerEntry) ([]Parlay, []Parlay) {
+    candidates := append([]ValueBet(nil), valueBets...)
+    sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Value > candidates[j].Value })
+    if len(candidates) > maxParlayCandidates {
+        candidates = candidates[:maxParlayCandidates]
+    }
+
+    var parlays, sameGame []Parlay
+    var extend func(start int, legs []ValueBet)
+    extend = func(start int, legs []ValueBet) {
+        if len(legs) >= 2 {
+            parlay := pricer.price(append([]ValueBet(nil), legs...))
+            if parlay.SameGame {
+                // Worth asking for a quote only if the legs' odds multiplied
+                // would beat fair odds
+                if parlay.JointProb*americanToDecimal(parlay.LegOdds) > 1 {
+                    sameGame = append(sameGame, parlay)
+                }
+            } else if parlay.EV > 0 {
+                parlays = append(parlays, parlay)
+            }
+        }
+        if len(legs) == maxLegs {
+            return
+        }
+        for i := start; i < len(candidates); i++ {
+            if compatibleLeg(legs, candidates[i]) {
+                extend(i+1, append(legs, candidates[i]))
+            }
+        }
+    }
+    extend(0, nil)
+
+    sort.SliceStable(parlays, func(i, j int) bool { return parlays[i].EV > parlays[j].EV })
+    var staked []Parlay
+    remaining := budget
+    book := newExposureBook()
+    book.addOpen(open)
+    for _, parlay := range parlays {
+        if len(staked) == config.TopN || remaining < 0.01 {
+            break
+        }
+        stake := math.Min(parlay.Kelly*config.KellyFraction, config.MaxStake) * config.Bankroll
+        parlay.Stake = roundStake(math.Min(stake, remaining))
+        keys := parlayKeys(parlay.Legs)
+        if room, binding := book.room(keys); parlay.Stake > room {
+            parlay.Stake, parlay.StakeNote = trimStake(parlay.Stake, room, "", binding+" exposure limit")
+        }
+        if parlay.Stake <= 0 {
+            continue
+        }
+        book.add(keys, parlay.Stake)
+        remaining -= parlay.Stake
+        staked = append(staked, parlay)
+    }
+
+    sort.SliceStable(sameGame, func(i, j int) bool {
+        return sameGame[i].JointProb*americanToDecimal(sameGame[i].LegOdds) >
+            sameGame[j].JointProb*americanToDecimal(sameGame[j].LegOdds)
+    })
+    if len(sameGame) > config.TopN {
+        sameGame = sameGame[:config.TopN]
+    }
+    return staked, sameGame
+}
+
+// compatibleLeg reports whether bet can join legs: same bookmaker, and not
+// a second selection in a market of a game already in the parlay
+func compatibleLeg(legs []ValueBet, bet ValueBet) bool {
+    for _, leg := range legs {
+        if leg.Bookmaker != bet.Bookmaker {
+            return false
+        }
+        if leg.GameID == bet.GameID && leg.Market == bet.Market {
+            if _, isProp := propStats[bet.Market]; !isProp || propPlayer(leg) == propPlayer(bet) {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// betOutcome recovers the outcome a value bet was priced from
+func betOutcome(bet ValueBet) Outcome {
+    outcome := Outcome{Name: bet.Team, Price: bet.Odds, Point: bet.Point}
+    if base, _ := splitMarketKey(bet.Market); base == "team_totals" || propStats[bet.Market] != nil {
+        if i := strings.LastIndex(bet.Team, " "); i >= 0 {
+            outcome.Description, outcome.Name = bet.Team[:i], bet.Team[i+1:]
+        }
+    }
+    return outcome
+}
+
+// propPlayer is the player a prop bet is on
+func propPlayer(bet ValueBet) string {
+    return betOutcome(bet).Description
+}
+
+// decimalToAmerican converts decimal odds to American odds
+func decimalToAmerican(decimal float64) float64 {
+    if decimal >= 2 {
+        return (decimal - 1) * 100
+    }
+    return -100 / (decimal - 1)
+}
+
+func displayParlay(index int, parlay Parlay) {
+    title := "Parlay"
+    if parlay.SameGame {
+        title = "Same-Game Parlay"
+    }
+    fmt.Printf("\n%s #%d (%s, %d legs):\n", title, index, parlay.Bookmaker, len(parlay.Legs))
+    for _, leg := range parlay.Legs {
+        fmt.Printf("  %-40s %+7.0f  %s - %s\n", betSelection(leg), leg.Odds, marketLabel(leg.Market), leg.Game)
+    }
+    fmt.Printf("Joint Probability: %.1f%% (independent %.1f%%, correlation x%.2f)\n",
+        parlay.JointProb*100, parlay.IndependentProb*100, parlay.Correlation)
+    if parlay.SameGame {
+        fmt.Printf("Fair Odds: %+.0f (legs multiplied %+.0f)\n", parlay.FairOdds, parlay.LegOdds)
+        fmt.Printf("Get the book's quote - only worth betting if it pays more than %+.0f\n", parlay.FairOdds)
+        return
+    }
+    fmt.Printf("Parlay Odds: %+.0f (implied %.1f%%, fair %+.0f)\n", parlay.Odds, parlay.ImpliedProb*100, parlay.FairOdds)
+    fmt.Printf("Expected Value: %+.1f%% per unit staked\n", parlay.EV*100)
+    stake := fmt.Sprintf("%.2f", parlay.Stake)
+    if parlay.StakeNote != "" {
+        stake += " - " + parlay.StakeNote
+    }
+    fmt.Printf("Suggested Stake: %s\n", stake)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net/url"
+    "strings"
+    "sync"
+)
+
+// periodNames labels the period suffixes of market keys
+var periodNames = map[string]string{
+    "h1": "1st Half",
+    "h2": "2nd Half",
+    "q1": "1st Quarter",
+    "q2": "2nd Quarter",
+    "q3": "3rd Quarter",
+    "q4": "4th Quarter",
+}
+
+// marketNames labels the base markets
+var marketNames = map[string]string{
+    "h2h":         "Moneyline",
+    "spreads":     "Spread",
+    "totals":      "Total",
+    "team_totals": "Team Total",
+
+    "player_points":   "Player Points",
+    "player_rebounds": "Player Rebounds",
+    "player_assists":  "Player Assists",
+    "player_threes":   "Player Threes",
+}
+
+// marketLabel describes a market key for people, such as "Spread (1st
+// Quarter)" for spreads_q1
+func marketLabel(key string) string {
+    base, period := splitMarketKey(key)
+    label, ok := marketNames[base]
+    if !ok {
+        label = base
+    }
+    if period != "" {
+        label += " (" + periodNames[period] + ")"
+    }
+    return label
+}
+
+// isPeriodMarket reports whether key is a quarter or half market
+func isPeriodMarket(key string) bool {
+    _, period := splitMarketKey(key)
+    return period != ""
+}
+
+// addEventOdds fetches period_markets and prop_markets for every game not
+// yet over from the per-event odds endpoint, the only one that offers them,
+// and merges them into the games' bookmakers. A game whose event odds can't
+// be fetched keeps its full-game odds.
+func addEventOdds(ctx context.Context, client *oddsClient, sportKey string, games []Game) {
+    markets := append(append([]string{}, config.PeriodMarkets...), config.PropMarkets...)
+    if len(markets) == 0 {
+        return
+    }
+    query := url.Values{
+        "regions":    {config.Regions},
+        "markets":    {strings.Join(markets, ",")},
+        "oddsFormat": {"american"},
+    }
+
+    sem := make(chan struct{}, config.FetchParallelism)
+    var wg sync.WaitGroup
+    for i := range games {
+        game := &games[i]
+        if game.asOf().Sub(game.CommenceTime) > maxGameLength {
+            continue
+        }
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            select {
+            case sem <- struct{}{}:
+                defer func() { <-sem }()
+            case <-ctx.Done():
+                return
+            }
+
+            var event Game
+            path := "/" + sportKey + "/events/" + url.PathEscape(game.ID) + "/odds"
+            if err := client.getJSON(ctx, "odds", path, query, &event); err != nil {
+                log.Printf("WARNING: event odds for %s vs %s: %v", game.AwayTeam, game.HomeTeam, err)
+                return
+            }
+            mergeBookmakers(game, event.Bookmakers)
+        }()
+    }
+    wg.Wait()
+}
+
+// mergeBookmakers adds bookmakers' markets to game, matching books by key
+func mergeBookmakers(game *Game, bookmakers []Bookmaker) {
+    for _, bookmaker := range bookmakers {
+        merged := false
+        for i := range game.Bookmakers {
+            if game.Bookmakers[i].Key == bookmaker.Key {
+                game.Bookmakers[i].Markets = append(game.Bookmakers[i].Markets, bookmaker.Markets...)
+                merged = true
+                break
+            }
+        }
+        if !merged {
+            game.Bookmakers = append(game.Bookmakers, bookmaker)
+        }
+    }
+}
+
+// validatePeriodMarkets checks that markets holds only full-game markets and
+// periodMarkets only quarter and half markets
+func validatePeriodMarkets(markets, periodMarkets []string) error {
+    for _, key := range markets {
+        if isPeriodMarket(key) {
+            return fmt.Errorf("market %q is a period market, list it in period_markets", key)
+        }
+    }
+    for _, key := range periodMarkets {
+        if !isPeriodMarket(key) {
+            return fmt.Errorf("period_markets entry %q is not a quarter or half market", key)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// pipelineResult is everything one analysis needs. Sources that failed but
+// weren't required are listed in Degraded with their error.
+type pipelineResult struct {
+    FetchedAt  time.Time
+    Stats      map[string]TeamStats
+    LiveScores map[string]LiveGameState
+    Schedule   teamSchedule
+    Injuries   []Injury
+    Players    []PlayerStats
+    PlayerLogs *playerLogs
+    Games      []Game
+    Degraded   map[string]string
+}
+
+// fetchTask is one independent upstream source
+type fetchTask struct {
+    name     string
+    required bool
+    run      func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error
+}
+
+func pipelineTasks(client *oddsClient) []fetchTask {
+    tasks := []fetchTask{
+        {"stats", true, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+            stats, err := fetchStats(ctx)
+            mu.Lock()
+            result.Stats = stats
+            mu.Unlock()
+            return err
+        }},
+        {"odds", true, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+            games, err := fetchOdds(ctx, client, config.Sport)
+            mu.Lock()
+            result.Games = games
+            mu.Unlock()
+            return err
+        }},
+        // Without the scoreboard every game is priced as pre-game
+        {"live", false, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+            liveScores, err := fetchLiveScores(ctx)
+            mu.Lock()
+            result.LiveScores = liveScores
+            mu.Unlock()
+            return err
+        }},
+        // Without the schedule games are priced without rest and travel
+        {"schedule", false, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+            schedule, err := fetchSchedule(ctx)
+            mu.Lock()
+            result.Schedule = schedule
+            mu.Unlock()
+            return err
+        }},
+    }
+
+    // Without the injury report or player ratings teams are priced at full strength
+    if provider := newInjuryProvider(); provider != nil {
+        tasks = append(tasks,
+            fetchTask{"injuries", false, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+                injuries, err := provider.Injuries(ctx)
+                mu.Lock()
+                result.Injuries = injuries
+                mu.Unlock()
+                return err
+            }},
+            fetchTask{"players", false, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+                players, err := fetchPlayers(ctx)
+                mu.Lock()
+                result.Players = players
+                mu.Unlock()
+                return err
+            }},
+        )
+    }
+
+    // Without game logs player props aren't priced
+    if len(config.PropMarkets) > 0 {
+        tasks = append(tasks, fetchTask{"gamelogs", false, func(ctx context.Context, result *pipelineResult, mu *sync.Mutex) error {
+            logs, err := fetchPlayerLogs(ctx)
+            mu.Lock()
+            result.PlayerLogs = logs
+            mu.Unlock()
+            return err
+        }})
+    }
+    return tasks
+}
+
+// runPipeline fetches every source concurrently, at most fetch_parallelism at
+// a time, all under one fetch_deadline. It fails only if a required source
+// fails.
+func runPipeline(ctx context.Context, client *oddsClient) (*pipelineResult, error) {
+    ctx, cancel := context.WithTimeout(ctx, config.FetchDeadline)
+    defer cancel()
+
+    result := &pipelineResult{
+        FetchedAt: time.Now(),
+        Degraded:  make(map[string]string),
+    }
+    var mu sync.Mutex
+    var failed []string
+
+    sem := make(chan struct{}, config.FetchParallelism)
+    var wg sync.WaitGroup
+    for _, task := range pipelineTasks(client) {
+        task := task
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            select {
+            case sem <- struct{}{}:
+                defer func() { <-sem }()
+            case <-ctx.Done():
+                mu.Lock()
+                result.Degraded[task.name] = ctx.Err().Error()
+                if task.required {
+                    failed = append(failed, fmt.Sprintf("%s: %v", task.name, ctx.Err()))
+                }
+                mu.Unlock()
+                return
+            }
+
+            if err := task.run(ctx, result, &mu); err != nil {
+                mu.Lock()
+                result.Degraded[task.name] = redact(err.Error())
+                if task.required {
+                    failed = append(failed, fmt.Sprintf("%s: %v", task.name, err))
+                }
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    if len(failed) > 0 {
+        sort.Strings(failed)
+        return result, fmt.Errorf("error fetching %s", strings.Join(failed, "; "))
+    }
+    if result.LiveScores == nil {
+        result.LiveScores = map[string]LiveGameState{}
+    }
+    result.Stats = applyInjuries(result.Stats, result.Injuries, result.Players)
+    result.Stats = applyRatings(result.Stats, solveRatings(result.Schedule, config.RatingRidge))
+    result.PlayerLogs = result.PlayerLogs.excludeInjured(result.Injuries)
+    return result, nil
+}
+
+// degradedSummary formats degraded sources for display, "" if there are none
+func (r *pipelineResult) degradedSummary() string {
+    if len(r.Degraded) == 0 {
+        return ""
+    }
+    names := make([]string, 0, len(r.Degraded))
+    for name := range r.Degraded {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    var parts []string
+    for _, name := range names {
+        parts = append(parts, fmt.Sprintf("%s (%s)", name, r.Degraded[name]))
+    }
+    return strings.Join(parts, ", ")
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "hash/fnv"
+    "math"
+    "math/rand"
+    "sort"
+    "strings"
+)
+
+// PlayerGameLog is one player's box score line in one game
+type PlayerGameLog struct {
+    Player   string  `json:"player"`
+    Team     string  `json:"team"`
+    GameDate string  `json:"game_date"`
+    Matchup  string  `json:"matchup"`
+    Minutes  float64 `json:"minutes"`
+    Points   float64 `json:"points"`
+    Rebounds float64 `json:"rebounds"`
+    Assists  float64 `json:"assists"`
+    Threes   float64 `json:"threes"`
+}
+
+// propStats maps the-odds-api's player prop markets to the stat they settle on
+var propStats = map[string]func(PlayerGameLog) float64{
+    "player_points":   func(l PlayerGameLog) float64 { return l.Points },
+    "player_rebounds": func(l PlayerGameLog) float64 { return l.Rebounds },
+    "player_assists":  func(l PlayerGameLog) float64 { return l.Assists },
+    "player_threes":   func(l PlayerGameLog) float64 { return l.Threes },
+}
+
+// Sample sizes behind a projection
+const (
+    minPropGames       = 5
+    propRecentGames    = 10
+    opponentPriorGames = 10.0
+)
+
+// playerLogs holds every player's games, most recent first, keyed by
+// normalized player name, and each team's stats allowed
+type playerLogs struct {
+    Players map[string][]PlayerGameLog
+    Allowed map[string]map[string]float64
+}
+
+// newPlayerLogs indexes logs by player and works out how much of each prop
+// stat every team allows relative to the league, shrunk toward average by
+// opponentPriorGames
+func newPlayerLogs(logs []PlayerGameLog) *playerLogs {
+    if len(logs) == 0 {
+        return nil
+    }
+    players := make(map[string][]PlayerGameLog)
+    for _, entry := range logs {
+        if entry.Minutes <= 0 {
+            continue
+        }
+        key := normalizePlayerName(entry.Player)
+        players[key] = append(players[key], entry)
+    }
+    for _, games := range players {
+        sort.SliceStable(games, func(i, j int) bool { return games[i].GameDate > games[j].GameDate })
+    }
+
+    allowed := make(map[string]map[string]float64, len(propStats))
+    for market, stat := range propStats {
+        // Totals each defense gave up, one per game
+        byGame := make(map[string]float64)
+        for _, entry := range logs {
+            byGame[matchupOpponent(entry.Matchup)+"|"+entry.GameDate] += stat(entry)
+        }
+        sums, games := make(map[string]float64), make(map[string]int)
+        league := 0.0
+        for key, total := range byGame {
+            team := key[:strings.Index(key, "|")]
+            sums[team] += total
+            games[team]++
+            league += total
+        }
+        league /= float64(len(byGame))
+
+        factors := make(map[string]float64, len(sums))
+        for team, sum := range sums {
+            n := float64(games[team])
+            if league > 0 {
+                factors[canonicalTeamName(team)] = (sum/league + opponentPriorGames) / (n + opponentPriorGames)
+            }
+        }
+        allowed[market] = factors
+    }
+    return &playerLogs{Players: players, Allowed: allowed}
+}
+
+// matchupOpponent is the opponent's abbreviation in "BOS vs. NYK" or "BOS @ NYK"
+func matchupOpponent(matchup string) string {
+    fields := strings.Fields(matchup)
+    if len(fields) == 0 {
+        return ""
+    }
+    return fields[len(fields)-1]
+}
+
+// excludeInjured drops players the injury report expects to sit out at
+// least half the time; books void their props if they don't play
+func (p *playerLogs) excludeInjured(injuries []Injury) *playerLogs {
+    if p == nil || len(injuries) == 0 {
+        return p
+    }
+    players := make(map[string][]PlayerGameLog, len(p.Players))
+    for name, games := range p.Players {
+        players[name] = games
+    }
+    for _, injury := range injuries {
+        if missProbability(injury.Status) >= 0.5 {
+            delete(players, normalizePlayerName(injury.Player))
+        }
+    }
+    return &playerLogs{Players: players, Allowed: p.Allowed}
+}
+
+// propProjection is the distribution of a player's stat in one game
+type propProjection struct {
+    Mean    float64
+    StdDev  float64
+    Minutes float64
+}
+
+// projectProp projects stat over games against opponent: the player's
+// per-minute rate over the season, times minutes from recent games
+// weighted by form_decay, times how much opponent allows. The spread is
+// the player's own game-to-game variation, at least Poisson.
+func projectProp(games []PlayerGameLog, stat func(PlayerGameLog) float64, opponentFactor float64) propProjection {
+    totalStat, totalMinutes := 0.0, 0.0
+    for _, game := range games {
+        totalStat += stat(game)
+        totalMinutes += game.Minutes
+    }
+    rate := totalStat / totalMinutes
+
+    minutes, weights, weight := 0.0, 0.0, 1.0
+    for i, game := range games {
+        if i == propRecentGames {
+            break
+        }
+        minutes += game.Minutes * weight
+        weights += weight
+        weight *= config.FormDecay
+    }
+    minutes /= weights
+
+    variance := 0.0
+    for _, game := range games {
+        residual := stat(game) - rate*game.Minutes
+        variance += residual * residual
+    }
+    variance /= float64(len(games))
+
+    mean := rate * minutes * opponentFactor
+    return propProjection{
+        Mean:    mean,
+        StdDev:  math.Sqrt(math.Max(variance, mean)),
+        Minutes: minutes,
+    }
+}
+
+// sideProbability is the chance the stat lands on side of line ("Over" or
+// "Under"), ignoring pushes on whole-number lines. Stats are whole numbers,
+// so the normal is continuity corrected.
+func (p propProjection) sideProbability(side string, line float64) float64 {
+    if p.StdDev <= 0 {
+        return 0
+    }
+    cdf := func(x float64) float64 { return 0.5 * (1 + math.Erf((x-p.Mean)/(p.StdDev*math.Sqrt2))) }
+    over := 1 - cdf(math.Floor(line)+0.5)
+    under := cdf(math.Ceil(line) - 0.5)
+    if over+under == 0 {
+        return 0
+    }
+    if side == "Under" {
+        return under / (over + under)
+    }
+    return over / (over + under)
+}
+
+// propInputs finds the games of the player an outcome is on and how much
+// of market's stat their opponent in game allows
+func (p *playerLogs) propInputs(game Game, market string, player string) ([]PlayerGameLog, float64, bool) {
+    stat := propStats[market]
+    games := p.Players[normalizePlayerName(player)]
+    if stat == nil || len(games) < minPropGames {
+        return nil, 0, false
+    }
+    var opponent string
+    switch canonicalTeamName(games[0].Team) {
+    case canonicalTeamName(game.HomeTeam):
+        opponent = game.AwayTeam
+    case canonicalTeamName(game.AwayTeam):
+        opponent = game.HomeTeam
+    default:
+        return nil, 0, false
+    }
+    factor, ok := p.Allowed[market][canonicalTeamName(opponent)]
+    if !ok {
+        factor = 1
+    }
+    return games, factor, true
+}
+
+// calculatePropValue prices the player prop outcomes in market, returning
+// those with an edge. Props are priced pre-game only.
+func calculatePropValue(game Game, bookmaker Bookmaker, market Market, logs *playerLogs) []ValueBet {
+    var valueBets []ValueBet
+    if logs == nil {
+        return valueBets
+    }
+    stat := propStats[market.Key]
+    for _, outcome := range market.Outcomes {
+        games, factor, ok := logs.propInputs(game, market.Key, outcome.Description)
+        if !ok {
+            continue
+        }
+        projection := projectProp(games, stat, factor)
+        prob := projection.sideProbability(outcome.Name, outcome.Point)
+        impliedProb := americanToImpliedProb(outcome.Price)
+        if value := prob - impliedProb; value > config.MinEdge {
+            valueBets = append(valueBets, ValueBet{
+                Game:           fmt.Sprintf("%s vs %s", game.AwayTeam, game.HomeTeam),
+                GameID:         game.ID,
+                CommenceTime:   game.CommenceTime,
+                Phase:          phasePreGame,
+                Bookmaker:      bookmaker.Key,
+                Market:         market.Key,
+                Team:           selectionName(outcome),
+                Point:          outcome.Point,
+                Odds:           outcome.Price,
+                ImpliedProb:    impliedProb,
+                HistoricalProb: prob,
+                Value:          value,
+                Confidence:     propConfidence(game, market.Key, outcome, games, stat, factor, impliedProb),
+                LastUpdate:     market.LastUpdate,
+                Projection:     projection.Mean,
+            })
+        }
+    }
+    return valueBets
+}
+
+// propConfidence is the probability the prop's edge is real: the player's
+// games are resampled and the projection rerun on each draw
+func propConfidence(game Game, market string, outcome Outcome, games []PlayerGameLog, stat func(PlayerGameLog) float64, factor, impliedProb float64) float64 {
+    hash := fnv.New64a()
+    hash.Write([]byte(game.ID + "|" + market + "|" + outcome.Description + outcome.Name))
+    rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+    // Recent games stay in order so minutes still weight the latest most
+    resampled := make([]PlayerGameLog, len(games))
+    positive := 0
+    for i := 0; i < config.BootstrapSamples; i++ {
+        for j := range resampled {
+            resampled[j] = games[rng.Intn(len(games))]
+        }
+        sort.SliceStable(resampled, func(a, b int) bool { return resampled[a].GameDate > resampled[b].GameDate })
+        if projectProp(resampled, stat, factor).sideProbability(outcome.Name, outcome.Point) > impliedProb {
+            positive++
+        }
+    }
+    return float64(positive) / float64(config.BootstrapSamples)
+}
+
+func fetchPlayerLogs(ctx context.Context) (*playerLogs, error) {
+    var combinedData CombinedData
+    if err := runStatsFetcher(ctx, "gamelogs", &combinedData); err != nil {
+        return nil, err
+    }
+    if len(combinedData.GameLogs) == 0 {
+        return nil, fmt.Errorf("Python script returned no player game logs")
+    }
+    return newPlayerLogs(combinedData.GameLogs), nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "sort"
+)
+
+// TeamRating is a team's opponent-adjusted efficiency per 100 possessions.
+// Defense is points allowed, so lower is better; Net is Offense - Defense.
+type TeamRating struct {
+    Offense float64 `json:"offense"`
+    Defense float64 `json:"defense"`
+    Net     float64 `json:"net"`
+    Pace    float64 `json:"pace"`
+    Games   int     `json:"games"`
+}
+
+// ratingsTable is the solved league: every rated team plus the league
+// averages and home edge the regression estimated alongside them
+type ratingsTable struct {
+    Teams        map[string]TeamRating `json:"teams"`
+    LeagueRating float64               `json:"league_rating"`
+    LeaguePace   float64               `json:"league_pace"`
+    HomeEdge     float64               `json:"home_edge"`
+}
+
+// solveRatings fits points per 100 possessions in every game as
+//
+//	league average + offense(team) - defense(opponent) +/- home edge / 2
+//
+// by ridge regression, which shrinks teams with few games toward average.
+// It returns nil if the schedule has no usable games.
+func solveRatings(schedule teamSchedule, ridge float64) *ratingsTable {
+    var names []string
+    for name := range schedule {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    index := make(map[string]int, len(names))
+    for i, name := range names {
+        index[name] = i
+    }
+
+    type observation struct {
+        team, opponent int
+        home           bool
+        rating         float64
+    }
+    var observations []observation
+    possessions := make([]float64, len(names))
+    games := make([]int, len(names))
+    sum := 0.0
+    for name, entries := range schedule {
+        for _, entry := range entries {
+            opponent, ok := index[entry.Opponent]
+            if !ok || entry.Possessions <= 0 {
+                continue
+            }
+            rating := 100 * entry.Points / entry.Possessions
+            observations = append(observations, observation{index[name], opponent, entry.Home, rating})
+            possessions[index[name]] += entry.Possessions
+            games[index[name]]++
+            sum += rating
+        }
+    }
+    if len(observations) == 0 {
+        return nil
+    }
+    league := sum / float64(len(observations))
+
+    // Unknowns: offense for each team, then defense, then the home edge
+    n := len(names)
+    size := 2*n + 1
+    a := make([][]float64, size)
+    for i := range a {
+        a[i] = make([]float64, size)
+    }
+    b := make([]float64, size)
+    for _, obs := range observations {
+        side := -0.5
+        if obs.home {
+            side = 0.5
+        }
+        cols := []int{obs.team, n + obs.opponent, 2 * n}
+        vals := []float64{1, -1, side}
+        for i, ci := range cols {
+            for j, cj := range cols {
+                a[ci][cj] += vals[i] * vals[j]
+            }
+            b[ci] += vals[i] * (obs.rating - league)
+        }
+    }
+    for i := 0; i < 2*n; i++ {
+        a[i][i] += ridge
+    }
+    // Keeps the system solvable if every game was played at one venue
+    a[2*n][2*n] += 1e-6
+
+    x, ok := solveLinear(a, b)
+    if !ok {
+        return nil
+    }
+
+    table := &ratingsTable{
+        Teams:        make(map[string]TeamRating, n),
+        LeagueRating: league,
+        HomeEdge:     x[2*n],
+    }
+    totalPossessions, totalGames := 0.0, 0
+    for i, name := range names {
+        if games[i] == 0 {
+            continue
+        }
+        offense := league + x[i]
+        defense := league - x[n+i]
+        table.Teams[name] = TeamRating{
+            Offense: offense,
+            Defense: defense,
+            Net:     offense - defense,
+            Pace:    possessions[i] / float64(games[i]),
+            Games:   games[i],
+        }
+        totalPossessions += possessions[i]
+        totalGames += games[i]
+    }
+    table.LeaguePace = totalPossessions / float64(totalGames)
+    return table
+}
+
+// solveLinear solves a x = b by Gaussian elimination with partial pivoting,
+// reporting false if a is singular
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+    n := len(b)
+    for col := 0; col < n; col++ {
+        pivot := col
+        for row := col + 1; row < n; row++ {
+            if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+                pivot = row
+            }
+        }
+        if math.Abs(a[pivot][col]) < 1e-12 {
+            return nil, false
+        }
+        a[col], a[pivot] = a[pivot], a[col]
+        b[col], b[pivot] = b[pivot], b[col]
+
+        for row := col + 1; row < n; row++ {
+            factor := a[row][col] / a[col][col]
+            for k := col; k < n; k++ {
+                a[row][k] -= factor * a[col][k]
+            }
+            b[row] -= factor * b[col]
+        }
+    }
+
+    x := make([]float64, n)
+    for row := n - 1; row >= 0; row-- {
+        sum := b[row]
+        for k := row + 1; k < n; k++ {
+            sum -= a[row][k] * x[k]
+        }
+        x[row] = sum / a[row][row]
+    }
+    return x, true
+}
+
+// applyRatings returns a copy of stats with each rated team's Rating set
+func applyRatings(stats map[string]TeamStats, table *ratingsTable) map[string]TeamStats {
+    if table == nil {
+        return stats
+    }
+    adjusted := make(map[string]TeamStats, len(stats))
+    for name, teamStats := range stats {
+        if rating, ok := table.Teams[canonicalTeamName(name)]; ok {
+            teamStats.Rating = &rating
+        }
+        adjusted[name] = teamStats
+    }
+    return adjusted
+}
+
+// adjustedNetRating is the team's net rating per 100 possessions with
+// tonight's injuries taken off, falling back to raw points per game
+func adjustedNetRating(stats TeamStats) float64 {
+    if stats.Rating == nil || stats.Rating.Pace <= 0 {
+        return stats.AvgPointsFor - stats.AvgPointsAgainst
+    }
+    return stats.Rating.Net - stats.InjuryImpact*100/stats.Rating.Pace
+}
+
+// ratingWinProbability is team's chance to beat opponent on a neutral
+// court: the expected margin over the game's possessions, scaled by how
+// widely NBA margins vary. Injuries are priced separately.
+func ratingWinProbability(team, opponent TeamStats) (float64, bool) {
+    if team.Rating == nil || opponent.Rating == nil {
+        return 0, false
+    }
+    pace := (team.Rating.Pace + opponent.Rating.Pace) / 2
+    margin := (team.Rating.Net - opponent.Rating.Net) * pace / 100
+    return 0.5 * (1 + math.Erf(margin/(config.MarginStdDev*math.Sqrt2))), true
+}
+
+func fetchRatings(ctx context.Context) (*ratingsTable, error) {
+    schedule, err := fetchSchedule(ctx)
+    if err != nil {
+        return nil, err
+    }
+    table := solveRatings(schedule, config.RatingRidge)
+    if table == nil {
+        return nil, fmt.Errorf("no games with box scores to rate")
+    }
+    return table, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+    "math"
+    "testing"
+)
+
+// ratingsLeague plays a double round robin in which every game scores
+// exactly 110 + offense(team) - defense(opponent) +/- home/2 per 100
+// possessions. Offense and defense each sum to zero, so the regression's
+// league average is 110 and, without a ridge, it should recover them.
+var (
+    ratingsOffense = map[string]float64{"Boston Celtics": 6, "Miami Heat": -1, "Utah Jazz": -4, "Denver Nuggets": -1}
+    ratingsDefense = map[string]float64{"Boston Celtics": 3, "Miami Heat": 2, "Utah Jazz": -6, "Denver Nuggets": 1}
+)
+
+const ratingsHomeEdge = 3.0
+
+func ratingsLeague() teamSchedule {
+    schedule := make(teamSchedule)
+    for home := range ratingsOffense {
+        for away := range ratingsOffense {
+            if home == away {
+                continue
+            }
+            homeRating := 110 + ratingsOffense[home] - ratingsDefense[away] + ratingsHomeEdge/2
+            awayRating := 110 + ratingsOffense[away] - ratingsDefense[home] - ratingsHomeEdge/2
+            schedule[home] = append(schedule[home], scheduleEntry{Home: true, Opponent: away, Points: homeRating, Possessions: 100})
+            schedule[away] = append(schedule[away], scheduleEntry{Opponent: home, Points: awayRating * 0.98, Possessions: 98})
+        }
+    }
+    return schedule
+}
+
+func TestSolveRatingsRecoversLeague(t *testing.T) {
+    table := solveRatings(ratingsLeague(), 1e-6)
+    if table == nil {
+        t.Fatal("solveRatings = nil")
+    }
+    if math.Abs(table.LeagueRating-110) > 1e-6 || math.Abs(table.HomeEdge-ratingsHomeEdge) > 1e-3 {
+        t.Errorf("league %.3f, home edge %.3f, want 110 and %.1f", table.LeagueRating, table.HomeEdge, ratingsHomeEdge)
+    }
+    if math.Abs(table.LeaguePace-99) > 1e-9 {
+        t.Errorf("league pace = %.3f, want 99", table.LeaguePace)
+    }
+    for name, offense := range ratingsOffense {
+        rating := table.Teams[name]
+        wantDefense := 110 - ratingsDefense[name]
+        if math.Abs(rating.Offense-(110+offense)) > 1e-3 || math.Abs(rating.Defense-wantDefense) > 1e-3 {
+            t.Errorf("%s = %.2f/%.2f, want %.2f/%.2f", name, rating.Offense, rating.Defense, 110+offense, wantDefense)
+        }
+        if rating.Net != rating.Offense-rating.Defense || rating.Games != 6 || rating.Pace != 99 {
+            t.Errorf("%s = %+v, want net offense - defense over 6 games at pace 99", name, rating)
+        }
+    }
+}
+
+func TestSolveRatings(t *testing.T) {
+    tests := []struct {
+        name     string
+        schedule teamSchedule
+        ridge    float64
+        check    func(t *testing.T, table *ratingsTable)
+    }{
+        {
+            name:  "no games",
+            ridge: 1,
+            check: func(t *testing.T, table *ratingsTable) {
+                if table != nil {
+                    t.Errorf("table = %+v, want nil", table)
+                }
+            },
+        },
+        {
+            name: "unknown opponents and missing possessions are skipped",
+            schedule: teamSchedule{
+                "Boston Celtics": {
+                    {Home: true, Opponent: "Miami Heat", Points: 112, Possessions: 100},
+                    {Opponent: "Toronto Raptors", Points: 150, Possessions: 100},
+                    {Opponent: "Miami Heat", Points: 105, Possessions: 0},
+                },
+                "Miami Heat": {{Opponent: "Boston Celtics", Points: 108, Possessions: 100}},
+            },
+            ridge: 1,
+            check: func(t *testing.T, table *ratingsTable) {
+                if table == nil || len(table.Teams) != 2 || table.Teams["Boston Celtics"].Games != 1 {
+                    t.Fatalf("table = %+v, want one game each for two teams", table)
+                }
+                if table.LeagueRating != 110 {
+                    t.Errorf("league = %.2f, want 110", table.LeagueRating)
+                }
+            },
+        },
+        {
+            name:     "ridge shrinks ratings toward average",
+            schedule: ratingsLeague(),
+            ridge:    20,
+            check: func(t *testing.T, table *ratingsTable) {
+                best, worst := table.Teams["Boston Celtics"], table.Teams["Utah Jazz"]
+                if best.Net <= 0 || best.Net >= 9 || worst.Net >= 0 || worst.Net <= -10 {
+                    t.Errorf("nets %.2f and %.2f, want shrunk toward 0 from 9 and -10", best.Net, worst.Net)
+                }
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tt.check(t, solveRatings(tt.schedule, tt.ridge))
+        })
+    }
+}
+
+func TestApplyRatings(t *testing.T) {
+    stats := map[string]TeamStats{
+        "BOS":     {WinRate: 0.7},
+        "Unknown": {WinRate: 0.4},
+    }
+    if got := applyRatings(stats, nil); got["BOS"].Rating != nil {
+        t.Errorf("nil table rated %+v", got["BOS"])
+    }
+
+    table := &ratingsTable{Teams: map[string]TeamRating{"Boston Celtics": {Net: 9, Pace: 99}}}
+    got := applyRatings(stats, table)
+    if got["BOS"].Rating == nil || got["BOS"].Rating.Net != 9 || got["BOS"].WinRate != 0.7 {
+        t.Errorf("BOS = %+v, want the Celtics' rating by abbreviation", got["BOS"])
+    }
+    if got["Unknown"].Rating != nil {
+        t.Errorf("Unknown = %+v, want no rating", got["Unknown"])
+    }
+    if stats["BOS"].Rating != nil {
+        t.Error("applyRatings changed its input")
+    }
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "time"
+)
+
+// ScheduleGame is one completed game from a team's point of view, as
+// reported by nba_stats_fetcher.py schedule
+type ScheduleGame struct {
+    Team        string  `json:"team"`
+    GameDate    string  `json:"game_date"`
+    Matchup     string  `json:"matchup"`
+    Points      float64 `json:"points"`
+    Possessions float64 `json:"possessions"`
+}
+
+// scheduleEntry is a ScheduleGame resolved against the team registry
+type scheduleEntry struct {
+    Date        time.Time
+    Home        bool
+    Arena       teamInfo
+    Opponent    string
+    Points      float64
+    Possessions float64
+}
+
+// teamSchedule holds each team's completed games in date order
+type teamSchedule map[string][]scheduleEntry
+
+// scheduleFactors describes how rested and travelled a team is for one game
+type scheduleFactors struct {
+    Known       bool
+    RestDays    int
+    BackToBack  bool
+    ThreeInFour bool
+    TravelMiles float64
+    ZoneShift   int
+}
+
+// Travel only tires a team that hasn't had this many days to recover
+const travelRecoveryDays = 2
+
+// Rest beyond this many days stops helping
+const maxRestDays = 3
+
+// Shifts smaller than this aren't worth reporting
+const minAdjustment = 0.001
+
+func fetchSchedule(ctx context.Context) (teamSchedule, error) {
+    var combinedData CombinedData
+    if err := runStatsFetcher(ctx, "schedule", &combinedData); err != nil {
+        return nil, err
+    }
+    if len(combinedData.Schedule) == 0 {
+        return nil, fmt.Errorf("Python script returned no schedule")
+    }
+    return newTeamSchedule(combinedData.Schedule), nil
+}
+
+// newTeamSchedule resolves raw game log rows, dropping any it can't place.
+// MATCHUP reads "BOS vs. NYK" for home games and "BOS @ NYK" for road games.
+func newTeamSchedule(games []ScheduleGame) teamSchedule {
+    schedule := make(teamSchedule)
+    for _, game := range games {
+        team, ok := lookupTeam(game.Team)
+        if !ok {
+            continue
+        }
+        date, err := time.Parse("2006-01-02", game.GameDate)
+        if err != nil {
+            continue
+        }
+        fields := strings.Fields(game.Matchup)
+        if len(fields) != 3 {
+            continue
+        }
+        opponent, ok := lookupTeam(fields[2])
+        if !ok {
+            continue
+        }
+        home := fields[1] == "vs."
+        arena := team
+        if !home {
+            arena = opponent
+        }
+        schedule[team.Name] = append(schedule[team.Name], scheduleEntry{
+            Date:        date,
+            Home:        home,
+            Arena:       arena,
+            Opponent:    opponent.Name,
+            Points:      game.Points,
+            Possessions: game.Possessions,
+        })
+    }
+
+    for _, entries := range schedule {
+        sort.Slice(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+    }
+    return schedule
+}
+
+// factors works out rest and travel for team going into game
+func (s teamSchedule) factors(team string, game Game) scheduleFactors {
+    arena, ok := lookupTeam(game.HomeTeam)
+    if !ok || game.CommenceTime.IsZero() {
+        return scheduleFactors{}
+    }
+    entries := s[canonicalTeamName(team)]
+
+    // Game dates are local to the arena
+    local := game.CommenceTime.In(time.FixedZone(arena.Abbrev, arena.UTCOffset*3600))
+    gameDate := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+    var last *scheduleEntry
+    recent := 0
+    for i := range entries {
+        if !entries[i].Date.Before(gameDate) {
+            break
+        }
+        last = &entries[i]
+        if gameDate.Sub(entries[i].Date) <= 3*24*time.Hour {
+            recent++
+        }
+    }
+    if last == nil {
+        return scheduleFactors{}
+    }
+
+    restDays := int(gameDate.Sub(last.Date).Hours()/24) - 1
+    factors := scheduleFactors{
+        Known:       true,
+        RestDays:    restDays,
+        BackToBack:  restDays == 0,
+        ThreeInFour: recent >= 2,
+    }
+    if restDays < travelRecoveryDays {
+        factors.TravelMiles = milesBetween(last.Arena, arena)
+        factors.ZoneShift = int(math.Abs(float64(arena.UTCOffset - last.Arena.UTCOffset)))
+    }
+    return factors
+}
+
+// scheduleAdjustments compares team's fatigue with its opponent's and
+// returns each factor's contribution to team's win probability
+func scheduleAdjustments(schedule teamSchedule, game Game, team string) []Adjustment {
+    if !config.ScheduleAdjustments || schedule == nil {
+        return nil
+    }
+    opponent := game.HomeTeam
+    if team == game.HomeTeam {
+        opponent = game.AwayTeam
+    }
+    own := schedule.factors(team, game)
+    opp := schedule.factors(opponent, game)
+    if !own.Known || !opp.Known {
+        return nil
+    }
+
+    var adjustments []Adjustment
+    add := func(factor string, value float64) {
+        if math.Abs(value) >= minAdjustment {
+            adjustments = append(adjustments, Adjustment{Factor: factor, Value: value})
+        }
+    }
+
+    add("back_to_back", config.BackToBackPenalty*(boolToFloat(opp.BackToBack)-boolToFloat(own.BackToBack)))
+    add("three_in_four", config.ThreeInFourPenalty*(boolToFloat(opp.ThreeInFour)-boolToFloat(own.ThreeInFour)))
+    if !own.BackToBack && !opp.BackToBack {
+        restEdge := min(own.RestDays, maxRestDays) - min(opp.RestDays, maxRestDays)
+        add("rest", config.RestDayValue*float64(restEdge))
+    }
+    add("travel", config.TravelPenalty*(opp.TravelMiles-own.TravelMiles)/1000)
+    add("time_zones", config.TimeZonePenalty*float64(opp.ZoneShift-own.ZoneShift))
+    return adjustments
+}
+
+// sumAdjustments totals the probability shift of a set of adjustments
+func sumAdjustments(adjustments []Adjustment) float64 {
+    total := 0.0
+    for _, adjustment := range adjustments {
+        total += adjustment.Value
+    }
+    return total
+}
+
+// formatAdjustments renders adjustments as "travel +0.8%, rest -1.0%"
+func formatAdjustments(adjustments []Adjustment) string {
+    parts := make([]string, 0, len(adjustments))
+    for _, adjustment := range adjustments {
+        parts = append(parts, fmt.Sprintf("%s %+.1f%%", adjustment.Factor, adjustment.Value*100))
+    }
+    return strings.Join(parts, ", ")
+}
+
+func boolToFloat(b bool) float64 {
+    if b {
+        return 1
+    }
+    return 0
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// dataStore keeps the latest snapshot so requests never hit upstream APIs directly
+type dataStore struct {
+    client *oddsClient
+    events *eventLog
+    alerts *alertManager
+
+    mu        sync.RWMutex
+    snap      *pipelineResult
+    bets      map[string]ValueBet
+    valueBets []ValueBet
+    lastErr   error
+}
+
+func newDataStore(client *oddsClient) *dataStore {
+    return &dataStore{
+        client: client,
+        events: newEventLog(1000),
+        bets:   make(map[string]ValueBet),
+    }
+}
+
+// refresh fetches stats, live scores and odds and swaps them in as a single snapshot
+func (s *dataStore) refresh(ctx context.Context) error {
+    result, err := runPipeline(ctx, s.client)
+    if err != nil {
+        s.setError(err)
+        return err
+    }
+
+    valueBets := findValueBets(result.Games, result.Stats, result.LiveScores, result.Schedule, result.PlayerLogs)
+    // Every consumer sees stakes trimmed to the exposure caps, counting the
+    // open bets in the ledger
+    pricer := newParlayPricer(result.Games, result.Stats, result.LiveScores, result.Schedule)
+    applyExposure(valueBets, openLedgerEntries(), pricer)
+    bets := make(map[string]ValueBet)
+    for _, bet := range valueBets {
+        bets[valueBetKey(bet)] = bet
+    }
+
+    s.mu.Lock()
+    prev := s.snap
+    prevBets := s.bets
+    s.snap = result
+    s.bets = bets
+    s.valueBets = valueBets
+    s.lastErr = nil
+    s.mu.Unlock()
+
+    prevLive := map[string]LiveGameState{}
+    if prev != nil {
+        prevLive = prev.LiveScores
+    }
+    publishDiff(s.events, prevBets, bets, prevLive, result.LiveScores)
+
+    if s.alerts != nil {
+        s.alerts.process(valueBets)
+    }
+    return nil
+}
+
+func (s *dataStore) setError(err error) {
+    s.mu.Lock()
+    s.lastErr = err
+    s.mu.Unlock()
+}
+
+// current returns the latest snapshot, or nil if no refresh has succeeded yet
+func (s *dataStore) current() *pipelineResult {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.snap
+}
+
+// currentBets returns the value bets found by the latest refresh, sized and
+// sorted by confidence
+func (s *dataStore) currentBets() []ValueBet {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.valueBets
+}
+
+// run refreshes the store every interval until ctx is cancelled
+func (s *dataStore) run(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if err := s.refresh(ctx); err != nil {
+                log.Printf("refresh failed, serving previous snapshot: %v", err)
+            } else if degraded := s.current().degradedSummary(); degraded != "" {
+                log.Printf("refreshed with degraded sources: %s", degraded)
+            }
+        }
+    }
+}
+
+type gameSummary struct {
+    ID       string         `json:"id"`
+    HomeTeam string         `json:"home_team"`
+    AwayTeam string         `json:"away_team"`
+    Live     *LiveGameState `json:"live,omitempty"`
+}
+
+type apiServer struct {
+    store *dataStore
+}
+
+func (a *apiServer) routes() *http.ServeMux {
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /games", a.handleGames)
+    mux.HandleFunc("GET /odds/{gameID}", a.handleOdds)
+    mux.HandleFunc("GET /value-bets", a.handleValueBets)
+    mux.HandleFunc("GET /live", a.handleLive)
+    mux.HandleFunc("GET /events", a.handleEvents)
+    mux.HandleFunc("GET /status", a.handleStatus)
+    return mux
+}
+
+// snapshotOrError writes a 503 if the store has not loaded any data yet
+func (a *apiServer) snapshotOrError(w http.ResponseWriter) *pipelineResult {
+    snap := a.store.current()
+    if snap == nil {
+        msg := "data not loaded yet"
+        a.store.mu.RLock()
+        if a.store.lastErr != nil {
+            msg = a.store.lastErr.Error()
+        }
+        a.store.mu.RUnlock()
+        writeError(w, http.StatusServiceUnavailable, msg)
+    }
+    return snap
+}
+
+func (a *apiServer) handleGames(w http.ResponseWriter, r *http.Request) {
+    snap := a.snapshotOrError(w)
+    if snap == nil {
+        return
+    }
+
+    games := make([]gameSummary, 0, len(snap.Games))
+    for _, game := range snap.Games {
+        summary := gameSummary{
+            ID:       game.ID,
+            HomeTeam: game.HomeTeam,
+            AwayTeam: game.AwayTeam,
+        }
+        gameKey := fmt.Sprintf("%s vs %s", game.AwayTeam, game.HomeTeam)
+        if liveGame, isLive := snap.LiveScores[gameKey]; isLive {
+            summary.Live = &liveGame
+        }
+        games = append(games, summary)
+    }
+
+    writeJSON(w, http.StatusOK, games)
+}
+
+func (a *apiServer) handleOdds(w http.ResponseWriter, r *http.Request) {
+    snap := a.snapshotOrError(w)
+    if snap == nil {
+        return
+    }
+
+    gameID := r.PathValue("gameID")
+    for _, game := range snap.Games {
+        if game.ID == gameID {
+            writeJSON(w, http.StatusOK, game)
+            return
+        }
+    }
+    writeError(w, http.StatusNotFound, fmt.Sprintf("game %q not found", gameID))
+}
+
+func (a *apiServer) handleValueBets(w http.ResponseWriter, r *http.Request) {
+    snap := a.snapshotOrError(w)
+    if snap == nil {
+        return
+    }
+
+    query := r.URL.Query()
+    book := query.Get("book")
+    if book == "" {
+        book = config.Bookmakers[0]
+    }
+    market := query.Get("market")
+
+    var minEdge float64
+    if raw := query.Get("minEdge"); raw != "" {
+        edge, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid minEdge %q", raw))
+            return
+        }
+        minEdge = edge
+    }
+
+    // Bets come from the last refresh, already sized and sorted, so a
+    // request never reruns the bootstrap or the simulation
+    valueBets := []ValueBet{}
+    for _, bet := range a.store.currentBets() {
+        if bet.Bookmaker != book {
+            continue
+        }
+        if market != "" && bet.Market != market {
+            continue
+        }
+        if bet.Value < minEdge {
+            continue
+        }
+        valueBets = append(valueBets, bet)
+    }
+
+    writeJSON(w, http.StatusOK, valueBets)
+}
+
+func (a *apiServer) handleLive(w http.ResponseWriter, r *http.Request) {
+    snap := a.snapshotOrError(w)
+    if snap == nil {
+        return
+    }
+    writeJSON(w, http.StatusOK, snap.LiveScores)
+}
+
+type statusResponse struct {
+    FetchedAt time.Time         `json:"fetched_at"`
+    Games     int               `json:"games"`
+    Teams     int               `json:"teams"`
+    LiveGames int               `json:"live_games"`
+    Degraded  map[string]string `json:"degraded"`
+}
+
+func (a *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+    snap := a.snapshotOrError(w)
+    if snap == nil {
+        return
+    }
+    writeJSON(w, http.StatusOK, statusResponse{
+        FetchedAt: snap.FetchedAt,
+        Games:     len(snap.Games),
+        Teams:     len(snap.Stats),
+        LiveGames: len(snap.LiveScores),
+        Degraded:  snap.Degraded,
+    })
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    if err := json.NewEncoder(w).Encode(v); err != nil {
+        log.Printf("error encoding response: %v", err)
+    }
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+    writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func runServe(ctx context.Context, args []string) error {
+    fs, configFlags := newFlagSet("serve",
+        "Serves games, odds, value bets, live scores and an event stream over HTTP from a cache\n"+
+            "that is refreshed in the background.")
+    addr := fs.String("addr", ":8080", "address to listen on")
+    interval := fs.Duration("refresh", 60*time.Second, "how often to refresh upstream data")
+    if err := parseFlags(fs, configFlags, args); err != nil {
+        return err
+    }
+
+    client, err := initClient()
+    if err != nil {
+        return err
+    }
+
+    store := newDataStore(client)
+    store.alerts = newAlertManager(config.Alerts)
+    if store.alerts != nil {
+        for _, n := range store.alerts.notifiers {
+            log.Printf("Alerts enabled: %s", n.Name())
+        }
+    }
+    log.Println("Loading initial data...")
+    if err := store.refresh(ctx); err != nil {
+        // Keep serving; the refresh loop will retry and handlers report 503 until then
+        log.Printf("initial refresh failed: %v", err)
+    }
+
+    go store.run(ctx, *interval)
+
+    server := &apiServer{store: store}
+    httpServer := &http.Server{
+        Addr:        *addr,
+        Handler:     server.routes(),
+        BaseContext: func(net.Listener) context.Context { return ctx },
+    }
+    go func() {
+        <-ctx.Done()
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        httpServer.Shutdown(shutdownCtx)
+    }()
+
+    log.Printf("Serving on %s (refresh every %s)", *addr, *interval)
+    if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+        return err
+    }
+    return nil
+}
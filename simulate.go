@@ -0,0 +1,423 @@
+package main
+
+import (
+    "fmt"
+    "hash/fnv"
+    "math"
+    "math/rand"
+    "strings"
+)
+
+// Regulation and overtime lengths in minutes
+const (
+    quarterMinutes  = 12.0
+    overtimeMinutes = 5.0
+    maxOvertimes    = 6
+)
+
+// simParams are the per-48-minute scoring rates the simulator plays out
+type simParams struct {
+    HomePoints float64
+    AwayPoints float64
+    MarginSD   float64
+    TotalSD    float64
+}
+
+// simParamsFor expects each team's points from its offense against the
+// opponent's defense at the game's pace, falling back to points per game
+// for unrated teams. Home court is left to anchorMargin.
+func simParamsFor(game Game, teamStats map[string]TeamStats) simParams {
+    home, away := teamStats[game.HomeTeam], teamStats[game.AwayTeam]
+    params := simParams{MarginSD: config.MarginStdDev, TotalSD: config.TotalStdDev}
+
+    if home.Rating != nil && away.Rating != nil && home.Rating.Pace > 0 && away.Rating.Pace > 0 {
+        league := leagueOffense(teamStats)
+        pace := (home.Rating.Pace + away.Rating.Pace) / 2
+        params.HomePoints = (home.Rating.Offense + away.Rating.Defense - league) * pace / 100
+        params.AwayPoints = (away.Rating.Offense + home.Rating.Defense - league) * pace / 100
+        // Injured players come off the team that's missing them; points per
+        // game already have them taken off
+        params.HomePoints -= home.InjuryImpact
+        params.AwayPoints -= away.InjuryImpact
+    } else {
+        params.HomePoints = (home.AvgPointsFor + away.AvgPointsAgainst) / 2
+        params.AwayPoints = (away.AvgPointsFor + home.AvgPointsAgainst) / 2
+    }
+    return params
+}
+
+// leagueOffense is the average offensive rating of the rated teams
+func leagueOffense(teamStats map[string]TeamStats) float64 {
+    sum, n := 0.0, 0
+    for _, stats := range teamStats {
+        if stats.Rating != nil {
+            sum += stats.Rating.Offense
+            n++
+        }
+    }
+    if n == 0 {
+        return 0
+    }
+    return sum / float64(n)
+}
+
+// anchorMargin moves the expected margin to the one implied by the model's
+// home win probability, keeping the total, so every market the simulator
+// prices agrees with the moneyline
+func (p *simParams) anchorMargin(homeWinProb float64) {
+    margin := expectedMargin(homeWinProb)
+    total := p.HomePoints + p.AwayPoints
+    p.HomePoints = (total + margin) / 2
+    p.AwayPoints = (total - margin) / 2
+}
+
+// simRun is one simulated game: points by quarter, with overtime counted
+// in the fourth
+type simRun struct {
+    Home [4]int
+    Away [4]int
+}
+
+// gameSim is the distribution of a game's score over many simulated runs.
+// A live game is simulated from its current score; quarters before
+// FirstPeriod were already under way and can't be priced.
+type gameSim struct {
+    Runs        []simRun
+    HomeStart   int
+    AwayStart   int
+    FirstPeriod int
+    Elapsed     float64
+}
+
+// simulateGame plays the game out minute by minute runs times. Each minute
+// adds normally distributed margin and total increments scaled so a full
+// game has the params' means and spreads; ties after regulation go to
+// five-minute overtimes.
+func simulateGame(game Game, params simParams, liveGame LiveGameState, inPlay bool, runs int) *gameSim {
+    hash := fnv.New64a()
+    hash.Write([]byte(game.ID))
+    rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+    sim := &gameSim{Runs: make([]simRun, runs)}
+    if inPlay {
+        sim.HomeStart, sim.AwayStart = liveGame.HomeScore, liveGame.AwayScore
+        sim.FirstPeriod = liveGame.Period
+        sim.Elapsed = minutesElapsed(liveGame.Period, liveGame.Clock)
+    }
+    elapsed := sim.Elapsed
+
+    marginRate := (params.HomePoints - params.AwayPoints) / 48
+    totalRate := (params.HomePoints + params.AwayPoints) / 48
+    marginSD := params.MarginSD / math.Sqrt(48)
+    totalSD := params.TotalSD / math.Sqrt(48)
+
+    for i := range sim.Runs {
+        run := &sim.Runs[i]
+        // Running scores are kept continuous and rounded as they go, so the
+        // quarters add up to the final. A minute's increment may be negative;
+        // clamping it would bias scoring upward, so only the running score is
+        // kept from going below zero.
+        home, away := float64(sim.HomeStart), float64(sim.AwayStart)
+        scoredHome, scoredAway := sim.HomeStart, sim.AwayStart
+        play := func(quarter int, minutes float64) {
+            margin := marginRate*minutes + rng.NormFloat64()*marginSD*math.Sqrt(minutes)
+            total := totalRate*minutes + rng.NormFloat64()*totalSD*math.Sqrt(minutes)
+            home = math.Max(0, home+(total+margin)/2)
+            away = math.Max(0, away+(total-margin)/2)
+            h, a := int(math.Round(home)), int(math.Round(away))
+            run.Home[quarter] += h - scoredHome
+            run.Away[quarter] += a - scoredAway
+            scoredHome, scoredAway = h, a
+        }
+
+        clock := elapsed
+        for clock < 4*quarterMinutes {
+            quarter := int(clock / quarterMinutes)
+            if quarter > 3 {
+                quarter = 3
+            }
+            step := math.Min(1, float64(quarter+1)*quarterMinutes-clock)
+            play(quarter, step)
+            clock += step
+        }
+
+        // Overtime still to play when the game is live in one
+        if remaining := 4*quarterMinutes + overtimeLength(liveGame.Period) - elapsed; inPlay && liveGame.Period > 4 && remaining > 0 {
+            play(3, remaining)
+        }
+        for ot := 0; scoredHome == scoredAway; ot++ {
+            if ot == maxOvertimes {
+                // Settle a marathon with one more basket
+                if rng.Intn(2) == 0 {
+                    run.Home[3] += 2
+                } else {
+                    run.Away[3] += 2
+                }
+                break
+            }
+            play(3, overtimeMinutes)
+        }
+    }
+    return sim
+}
+
+// newGameSim simulates game with its margin anchored to the model's
+// pre-game home win probability, or returns nil if either team is unknown
+func newGameSim(game Game, teamStats map[string]TeamStats, schedule teamSchedule, liveGame LiveGameState, inPlay bool) *gameSim {
+    _, homeOK := teamStats[game.HomeTeam]
+    _, awayOK := teamStats[game.AwayTeam]
+    if !homeOK || !awayOK {
+        return nil
+    }
+    params := simParamsFor(game, teamStats)
+    homeProb, _, _ := winProbability(game, game.HomeTeam, teamStats, schedule, LiveGameState{}, false)
+    params.anchorMargin(homeProb)
+    return simulateGame(game, params, liveGame, inPlay, config.SimRuns)
+}
+
+// minutesElapsed is how far into the game the clock is, overtime included
+func minutesElapsed(period int, clock string) float64 {
+    var min, sec float64
+    fmt.Sscanf(clock, "%f:%f", &min, &sec)
+    left := min + sec/60
+    if period <= 4 {
+        return float64(period)*quarterMinutes - left
+    }
+    return 4*quarterMinutes + float64(period-4)*overtimeMinutes - left
+}
+
+// overtimeLength is the minutes of overtime through the end of period
+func overtimeLength(period int) float64 {
+    if period <= 4 {
+        return 0
+    }
+    return float64(period-4) * overtimeMinutes
+}
+
+// segment is a span of quarters [from, to)
+type segment struct {
+    from, to int
+}
+
+// periodSegments maps the-odds-api's market key suffixes to quarters
+var periodSegments = map[string]segment{
+    "":   {0, 4},
+    "h1": {0, 2},
+    "h2": {2, 4},
+    "q1": {0, 1},
+    "q2": {1, 2},
+    "q3": {2, 3},
+    "q4": {3, 4},
+}
+
+// splitMarketKey separates a market key such as "spreads_q1" into its base
+// market and period, "" for the full game
+func splitMarketKey(key string) (string, string) {
+    if i := strings.LastIndex(key, "_"); i >= 0 {
+        if _, ok := periodSegments[key[i+1:]]; ok {
+            return key[:i], key[i+1:]
+        }
+    }
+    return key, ""
+}
+
+// points is each side's score over seg in run, counting the live score
+// only for the full game
+func (s *gameSim) points(run simRun, seg segment) (float64, float64) {
+    home, away := 0, 0
+    for q := seg.from; q < seg.to; q++ {
+        home += run.Home[q]
+        away += run.Away[q]
+    }
+    if seg.from == 0 && seg.to == 4 {
+        home += s.HomeStart
+        away += s.AwayStart
+    }
+    return float64(home), float64(away)
+}
+
+// available reports whether seg can still be priced from the simulation
+func (s *gameSim) available(seg segment) bool {
+    return seg.from >= s.FirstPeriod || (seg.from == 0 && seg.to == 4)
+}
+
+// outcomeProbability is the chance outcome of market wins, ignoring pushes,
+// with shift points added to the home and away scores of every run. It
+// reports false for markets and periods the simulation can't price.
+func (s *gameSim) outcomeProbability(game Game, marketKey string, outcome Outcome, shiftHome, shiftAway float64) (float64, bool) {
+    settle, ok := s.settler(game, marketKey, outcome, shiftHome, shiftAway)
+    if !ok {
+        return 0, false
+    }
+
+    wins, losses := 0, 0
+    for _, run := range s.Runs {
+        r := settle(run)
+        if r > 0 {
+            wins++
+        } else if r < 0 {
+            losses++
+        }
+    }
+    if wins+losses == 0 {
+        return 0, false
+    }
+    return float64(wins) / float64(wins+losses), true
+}
+
+// settler returns a function that settles outcome of market in a run,
+// positive for a win, zero for a push and negative for a loss, or false if
+// the simulation can't settle it
+func (s *gameSim) settler(game Game, marketKey string, outcome Outcome, shiftHome, shiftAway float64) (func(run simRun) float64, bool) {
+    base, period := splitMarketKey(marketKey)
+    seg := periodSegments[period]
+    if !s.available(seg) {
+        return nil, false
+    }
+
+    var result func(home, away float64) float64
+    switch base {
+    case "h2h":
+        result = func(home, away float64) float64 { return sideMargin(game, outcome.Name, home, away) }
+    case "spreads":
+        result = func(home, away float64) float64 {
+            return sideMargin(game, outcome.Name, home, away) + outcome.Point
+        }
+    case "totals":
+        result = func(home, away float64) float64 { return overUnder(outcome.Name, home+away, outcome.Point) }
+    case "team_totals":
+        result = func(home, away float64) float64 {
+            if outcome.Description == game.HomeTeam {
+                return overUnder(outcome.Name, home, outcome.Point)
+            }
+            return overUnder(outcome.Name, away, outcome.Point)
+        }
+    default:
+        return nil, false
+    }
+    if base == "h2h" || base == "spreads" {
+        if outcome.Name != game.HomeTeam && outcome.Name != game.AwayTeam {
+            return nil, false
+        }
+    }
+    if base == "team_totals" && outcome.Description != game.HomeTeam && outcome.Description != game.AwayTeam {
+        return nil, false
+    }
+
+    return func(run simRun) float64 {
+        home, away := s.points(run, seg)
+        return result(home+shiftHome, away+shiftAway)
+    }, true
+}
+
+// sideMargin is team's margin over its opponent
+func sideMargin(game Game, team string, home, away float64) float64 {
+    if team == game.HomeTeam {
+        return home - away
+    }
+    return away - home
+}
+
+// overUnder is positive when side ("Over" or "Under") beats line
+func overUnder(side string, points, line float64) float64 {
+    if side == "Under" {
+        return line - points
+    }
+    return points - line
+}
+
+// selectionName is what an outcome backs: a team, Over or Under, or a team
+// and Over or Under for team totals
+func selectionName(outcome Outcome) string {
+    if outcome.Description != "" {
+        return outcome.Description + " " + outcome.Name
+    }
+    return outcome.Name
+}
+
+// betSelection is a bet's selection with its line, such as "Boston Celtics
+// -4.5" or "Over 221.5"
+func betSelection(bet ValueBet) string {
+    return formatSelection(bet.Market, bet.Team, bet.Point)
+}
+
+func formatSelection(marketKey, name string, point float64) string {
+    switch base, _ := splitMarketKey(marketKey); base {
+    case "spreads":
+        return fmt.Sprintf("%s %+g", name, point)
+    case "totals", "team_totals", "player_points", "player_rebounds", "player_assists", "player_threes":
+        return fmt.Sprintf("%s %g", name, point)
+    }
+    return name
+}
+
+// segmentShare is the fraction of regulation seg covers
+func segmentShare(seg segment) float64 {
+    return float64(seg.to-seg.from) / 4
+}
+
+// simulatedConfidence is the probability that outcome's true chance beats
+// impliedProb. Each bootstrap draw of the two teams moves the expected
+// margin by what it does to the pre-game win probability, and the expected
+// total by its sampling error, and the simulated runs are shifted to match.
+func simulatedConfidence(game Game, sim *gameSim, marketKey string, outcome Outcome, teamStats map[string]TeamStats, schedule teamSchedule, impliedProb float64) float64 {
+    _, period := splitMarketKey(marketKey)
+    share := segmentShare(periodSegments[period])
+    if s := sim.remainingShare(); period == "" && s < share {
+        share = s
+    }
+
+    hash := fnv.New64a()
+    hash.Write([]byte(game.ID + "|" + marketKey + "|" + outcome.Description + outcome.Name))
+    rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+    baseProb, _, _ := winProbability(game, game.HomeTeam, teamStats, schedule, LiveGameState{}, false)
+    baseMargin := expectedMargin(baseProb)
+    totalErr := config.TotalStdDev / math.Sqrt(float64(sampleGames(teamStats[game.HomeTeam], teamStats[game.AwayTeam])))
+
+    resampled := make(map[string]TeamStats, len(teamStats))
+    for name, stats := range teamStats {
+        resampled[name] = stats
+    }
+
+    positive := 0
+    for i := 0; i < config.BootstrapSamples; i++ {
+        for _, team := range []string{game.HomeTeam, game.AwayTeam} {
+            if stats, ok := teamStats[team]; ok {
+                resampled[team] = resampleTeamStats(stats, rng)
+            }
+        }
+        prob, _, _ := winProbability(game, game.HomeTeam, resampled, schedule, LiveGameState{}, false)
+        margin := (expectedMargin(prob) - baseMargin) * share
+        total := rng.NormFloat64() * totalErr * share
+        if p, ok := sim.outcomeProbability(game, marketKey, outcome, (total+margin)/2, (total-margin)/2); ok && p > impliedProb {
+            positive++
+        }
+    }
+    return float64(positive) / float64(config.BootstrapSamples)
+}
+
+// expectedMargin is the home margin that gives homeWinProb
+func expectedMargin(homeWinProb float64) float64 {
+    prob := math.Min(math.Max(homeWinProb, 0.01), 0.99)
+    return config.MarginStdDev * math.Sqrt2 * math.Erfinv(2*prob-1)
+}
+
+// sampleGames is the smaller of the two teams' games played
+func sampleGames(home, away TeamStats) int {
+    games := 0
+    for _, stats := range []TeamStats{home, away} {
+        if n := stats.Home.Games + stats.Away.Games; n > 0 && (games == 0 || n < games) {
+            games = n
+        }
+    }
+    if games == 0 {
+        return assumedSeasonGames
+    }
+    return games
+}
+
+// remainingShare is the fraction of regulation still to be simulated
+func (s *gameSim) remainingShare() float64 {
+    return math.Max(0, 4*quarterMinutes-s.Elapsed) / (4 * quarterMinutes)
+}
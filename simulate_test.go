@@ -0,0 +1,69 @@
+package main
+
+import (
+    "math"
+    "testing"
+)
+
+// The first three quarters can't go to overtime, so their means should be
+// exactly three quarters of the params'; the full game adds overtime to
+// ties, which can only raise the total.
+func TestSimulateGameMatchesParams(t *testing.T) {
+    params := simParams{HomePoints: 115, AwayPoints: 110, MarginSD: 12, TotalSD: 18}
+    const runs = 20000
+    sim := simulateGame(Game{ID: "test"}, params, LiveGameState{}, false, runs)
+
+    var threeTotal, threeMargin, total, margin float64
+    for _, run := range sim.Runs {
+        for q := 0; q < 4; q++ {
+            home, away := float64(run.Home[q]), float64(run.Away[q])
+            if q < 3 {
+                threeTotal += home + away
+                threeMargin += home - away
+            }
+            total += home + away
+            margin += home - away
+        }
+    }
+    threeTotal /= runs
+    threeMargin /= runs
+    total /= runs
+    margin /= runs
+
+    // Four standard errors of the mean over three quarters
+    totalTol := 4 * params.TotalSD * math.Sqrt(0.75) / math.Sqrt(runs)
+    marginTol := 4 * params.MarginSD * math.Sqrt(0.75) / math.Sqrt(runs)
+    if want := 0.75 * (params.HomePoints + params.AwayPoints); math.Abs(threeTotal-want) > totalTol {
+        t.Errorf("three-quarter total = %.2f, want %.2f +/- %.2f", threeTotal, want, totalTol)
+    }
+    if want := 0.75 * (params.HomePoints - params.AwayPoints); math.Abs(threeMargin-want) > marginTol {
+        t.Errorf("three-quarter margin = %.2f, want %.2f +/- %.2f", threeMargin, want, marginTol)
+    }
+
+    // Overtime is played in about one game in sixteen at this margin, and
+    // adds around 20 points when it is
+    want := params.HomePoints + params.AwayPoints
+    if total < want-0.5 || total > want+2 {
+        t.Errorf("total = %.2f, want %.2f plus a little overtime", total, want)
+    }
+    if want := params.HomePoints - params.AwayPoints; math.Abs(margin-want) > 0.5 {
+        t.Errorf("margin = %.2f, want %.2f", margin, want)
+    }
+}
+
+// An Over at the expected total is close to a coin flip, nudged up only by
+// overtime
+func TestSimulatedOverAtExpectedTotal(t *testing.T) {
+    params := simParams{HomePoints: 115, AwayPoints: 110, MarginSD: 12, TotalSD: 18}
+    game := Game{ID: "test", HomeTeam: "Home", AwayTeam: "Away"}
+    sim := simulateGame(game, params, LiveGameState{}, false, 20000)
+
+    over := Outcome{Name: "Over", Point: 225.5}
+    prob, ok := sim.outcomeProbability(game, "totals", over, 0, 0)
+    if !ok {
+        t.Fatal("totals not priced")
+    }
+    if prob < 0.46 || prob > 0.56 {
+        t.Errorf("P(Over 225.5) = %.3f, want about 0.5", prob)
+    }
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+    "fmt"
+    "time"
+)
+
+// marketStaleness explains why a bookmaker's market looks stale, or returns ""
+// if its prices are current. A market is stale when it was last updated long
+// before we fetched it, or when it lags well behind the freshest book quoting
+// the same market on the same game.
+func marketStaleness(game Game, bookmaker Bookmaker, market Market) string {
+    if market.LastUpdate.IsZero() || game.FetchedAt.IsZero() {
+        return ""
+    }
+
+    age := game.FetchedAt.Sub(market.LastUpdate)
+    if age > config.StaleAfter {
+        return fmt.Sprintf("last updated %s before fetch", age.Round(time.Second))
+    }
+
+    var freshest time.Time
+    freshestBook := ""
+    for _, other := range game.Bookmakers {
+        if other.Key == bookmaker.Key {
+            continue
+        }
+        for _, otherMarket := range other.Markets {
+            if otherMarket.Key == market.Key && otherMarket.LastUpdate.After(freshest) {
+                freshest = otherMarket.LastUpdate
+                freshestBook = other.Title
+            }
+        }
+    }
+
+    if lag := freshest.Sub(market.LastUpdate); freshestBook != "" && lag > config.StaleLag {
+        return fmt.Sprintf("%s behind %s", lag.Round(time.Second), freshestBook)
+    }
+    return ""
+}
+
+// applyStaleness marks bet as stale. It reports false if the stale policy
+// excludes the bet entirely; otherwise its confidence is kept and betTier
+// caps its recommendation at stale_tier.
+func applyStaleness(bet *ValueBet, reason string) bool {
+    if reason == "" {
+        return true
+    }
+    if config.StalePolicy == "exclude" {
+        return false
+    }
+    bet.Stale = true
+    bet.StaleReason = reason
+    return true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "math"
+    "strings"
+)
+
+// teamInfo is one franchise and its home arena. UTCOffset is the arena's
+// standard-time offset in hours, enough to count time zones crossed.
+type teamInfo struct {
+    Name      string
+    Abbrev    string
+    Aliases   []string
+    Lat       float64
+    Lon       float64
+    UTCOffset int
+}
+
+// teamRegistry is every NBA team, keyed by the names the odds API uses
+var teamRegistry = []teamInfo{
+    {Name: "Atlanta Hawks", Abbrev: "ATL", Lat: 33.7573, Lon: -84.3963, UTCOffset: -5},
+    {Name: "Boston Celtics", Abbrev: "BOS", Lat: 42.3662, Lon: -71.0621, UTCOffset: -5},
+    {Name: "Brooklyn Nets", Abbrev: "BKN", Lat: 40.6826, Lon: -73.9754, UTCOffset: -5},
+    {Name: "Charlotte Hornets", Abbrev: "CHA", Lat: 35.2251, Lon: -80.8392, UTCOffset: -5},
+    {Name: "Chicago Bulls", Abbrev: "CHI", Lat: 41.8807, Lon: -87.6742, UTCOffset: -6},
+    {Name: "Cleveland Cavaliers", Abbrev: "CLE", Lat: 41.4965, Lon: -81.6882, UTCOffset: -5},
+    {Name: "Dallas Mavericks", Abbrev: "DAL", Lat: 32.7905, Lon: -96.8103, UTCOffset: -6},
+    {Name: "Denver Nuggets", Abbrev: "DEN", Lat: 39.7487, Lon: -105.0077, UTCOffset: -7},
+    {Name: "Detroit Pistons", Abbrev: "DET", Lat: 42.3410, Lon: -83.0551, UTCOffset: -5},
+    {Name: "Golden State Warriors", Abbrev: "GSW", Lat: 37.7680, Lon: -122.3877, UTCOffset: -8},
+    {Name: "Houston Rockets", Abbrev: "HOU", Lat: 29.7508, Lon: -95.3621, UTCOffset: -6},
+    {Name: "Indiana Pacers", Abbrev: "IND", Lat: 39.7640, Lon: -86.1555, UTCOffset: -5},
+    {Name: "Los Angeles Clippers", Abbrev: "LAC", Aliases: []string{"LA Clippers"}, Lat: 33.9450, Lon: -118.3414, UTCOffset: -8},
+    {Name: "Los Angeles Lakers", Abbrev: "LAL", Aliases: []string{"LA Lakers"}, Lat: 34.0430, Lon: -118.2673, UTCOffset: -8},
+    {Name: "Memphis Grizzlies", Abbrev: "MEM", Lat: 35.1382, Lon: -90.0506, UTCOffset: -6},
+    {Name: "Miami Heat", Abbrev: "MIA", Lat: 25.7814, Lon: -80.1870, UTCOffset: -5},
+    {Name: "Milwaukee Bucks", Abbrev: "MIL", Lat: 43.0451, Lon: -87.9172, UTCOffset: -6},
+    {Name: "Minnesota Timberwolves", Abbrev: "MIN", Lat: 44.9795, Lon: -93.2761, UTCOffset: -6},
+    {Name: "New Orleans Pelicans", Abbrev: "NOP", Lat: 29.9490, Lon: -90.0821, UTCOffset: -6},
+    {Name: "New York Knicks", Abbrev: "NYK", Lat: 40.7505, Lon: -73.9934, UTCOffset: -5},
+    {Name: "Oklahoma City Thunder", Abbrev: "OKC", Lat: 35.4634, Lon: -97.5151, UTCOffset: -6},
+    {Name: "Orlando Magic", Abbrev: "ORL", Lat: 28.5392, Lon: -81.3839, UTCOffset: -5},
+    {Name: "Philadelphia 76ers", Abbrev: "PHI", Lat: 39.9012, Lon: -75.1720, UTCOffset: -5},
+    {Name: "Phoenix Suns", Abbrev: "PHX", Lat: 33.4457, Lon: -112.0712, UTCOffset: -7},
+    {Name: "Portland Trail Blazers", Abbrev: "POR", Lat: 45.5316, Lon: -122.6668, UTCOffset: -8},
+    {Name: "Sacramento Kings", Abbrev: "SAC", Lat: 38.5802, Lon: -121.4997, UTCOffset: -8},
+    {Name: "San Antonio Spurs", Abbrev: "SAS", Lat: 29.4270, Lon: -98.4375, UTCOffset: -6},
+    {Name: "Toronto Raptors", Abbrev: "TOR", Lat: 43.6435, Lon: -79.3791, UTCOffset: -5},
+    {Name: "Utah Jazz", Abbrev: "UTA", Lat: 40.7683, Lon: -111.9011, UTCOffset: -7},
+    {Name: "Washington Wizards", Abbrev: "WAS", Lat: 38.8981, Lon: -77.0209, UTCOffset: -5},
+}
+
+// lookupTeam finds a team by full name, alias or abbreviation
+func lookupTeam(name string) (teamInfo, bool) {
+    name = strings.TrimSpace(name)
+    for _, team := range teamRegistry {
+        if strings.EqualFold(team.Name, name) || strings.EqualFold(team.Abbrev, name) {
+            return team, true
+        }
+        for _, alias := range team.Aliases {
+            if strings.EqualFold(alias, name) {
+                return team, true
+            }
+        }
+    }
+    return teamInfo{}, false
+}
+
+// canonicalTeamName maps any known spelling to the registry name, leaving
+// unknown names untouched
+func canonicalTeamName(name string) string {
+    if team, ok := lookupTeam(name); ok {
+        return team.Name
+    }
+    return name
+}
+
+// milesBetween is the great-circle distance between two arenas
+func milesBetween(from, to teamInfo) float64 {
+    const earthRadiusMiles = 3958.8
+    lat1 := from.Lat * math.Pi / 180
+    lat2 := to.Lat * math.Pi / 180
+    dLat := lat2 - lat1
+    dLon := (to.Lon - from.Lon) * math.Pi / 180
+
+    a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+    return 2 * earthRadiusMiles * math.Asin(math.Sqrt(a))
+}
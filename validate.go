@@ -0,0 +1,119 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "math"
+    "sort"
+    "strings"
+)
+
+// Bounds no real NBA team's season averages fall outside
+const (
+    leagueTeams   = 30
+    minTeamPoints = 80.0
+    maxTeamPoints = 140.0
+    maxNetRating  = 20.0
+)
+
+// validateTeamStats checks ingested stats against the team registry and
+// plausible ranges. It returns the teams that passed, keyed by registry name
+// with opponents renamed to match, and a diagnostic for every problem found.
+func validateTeamStats(stats map[string]TeamStats) (map[string]TeamStats, []string) {
+    names := make([]string, 0, len(stats))
+    for name := range stats {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    valid := make(map[string]TeamStats, len(stats))
+    var problems []string
+    for _, name := range names {
+        teamStats := stats[name]
+        team, ok := lookupTeam(name)
+        if !ok {
+            problems = append(problems, fmt.Sprintf("%q is not an NBA team in the registry", name))
+            continue
+        }
+        if _, dup := valid[team.Name]; dup {
+            problems = append(problems, fmt.Sprintf("%q duplicates %s", name, team.Name))
+            continue
+        }
+        if problem := teamStatsProblem(teamStats); problem != "" {
+            problems = append(problems, fmt.Sprintf("%s: %s", team.Name, problem))
+            continue
+        }
+
+        recent := make([]RecentGame, len(teamStats.RecentGames))
+        for i, game := range teamStats.RecentGames {
+            game.Opponent = canonicalTeamName(game.Opponent)
+            recent[i] = game
+        }
+        teamStats.RecentGames = recent
+        valid[team.Name] = teamStats
+    }
+
+    if len(stats) != leagueTeams {
+        var missing []string
+        for _, team := range teamRegistry {
+            if _, ok := valid[team.Name]; !ok {
+                missing = append(missing, team.Abbrev)
+            }
+        }
+        problem := fmt.Sprintf("expected %d teams, got %d", leagueTeams, len(stats))
+        if len(missing) > 0 {
+            problem += fmt.Sprintf(" (no valid stats for %s)", strings.Join(missing, ", "))
+        }
+        problems = append(problems, problem)
+    }
+    return valid, problems
+}
+
+// teamStatsProblem describes the first implausible value in teamStats, or
+// returns "" if everything is in range
+func teamStatsProblem(teamStats TeamStats) string {
+    if !inUnitRange(teamStats.WinRate) {
+        return fmt.Sprintf("win rate %.3f outside [0, 1]", teamStats.WinRate)
+    }
+    for _, points := range []float64{teamStats.AvgPointsFor, teamStats.AvgPointsAgainst} {
+        if math.IsNaN(points) || points < minTeamPoints || points > maxTeamPoints {
+            return fmt.Sprintf("points for %.1f / against %.1f outside [%.0f, %.0f], check the stats column mapping",
+                teamStats.AvgPointsFor, teamStats.AvgPointsAgainst, minTeamPoints, maxTeamPoints)
+        }
+    }
+    if net := teamStats.AvgPointsFor - teamStats.AvgPointsAgainst; math.Abs(net) > maxNetRating {
+        return fmt.Sprintf("net rating %+.1f outside ±%.0f", net, maxNetRating)
+    }
+    if !inUnitRange(teamStats.Home.WinRate) || !inUnitRange(teamStats.Away.WinRate) {
+        return fmt.Sprintf("home/away win rate %.3f/%.3f outside [0, 1]", teamStats.Home.WinRate, teamStats.Away.WinRate)
+    }
+    return ""
+}
+
+func inUnitRange(v float64) bool {
+    return !math.IsNaN(v) && v >= 0 && v <= 1
+}
+
+// checkTeamStats applies stats_validation: strict fails on any problem, warn
+// logs problems and drops the teams that failed, off passes stats through
+func checkTeamStats(stats map[string]TeamStats) (map[string]TeamStats, error) {
+    if config.StatsValidation == "off" {
+        return stats, nil
+    }
+
+    valid, problems := validateTeamStats(stats)
+    if len(problems) == 0 {
+        return valid, nil
+    }
+    if config.StatsValidation == "strict" {
+        return nil, fmt.Errorf("invalid team stats: %s", strings.Join(problems, "; "))
+    }
+
+    for _, problem := range problems {
+        log.Printf("WARNING: team stats: %s", problem)
+    }
+    if len(valid) == 0 {
+        return nil, fmt.Errorf("no valid team stats")
+    }
+    return valid, nil
+}